@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/geoip"
+	"github.com/iambakhodir/short-link/domain/random"
+	"github.com/iambakhodir/short-link/domain/reserved"
 	_linkHttpDelivery "github.com/iambakhodir/short-link/link/delivery/http"
 	_linkHttpMiddleware "github.com/iambakhodir/short-link/link/delivery/http/middleware"
 	_linkRepo "github.com/iambakhodir/short-link/link/repository/mysql"
@@ -11,7 +16,11 @@ import (
 	"github.com/labstack/echo"
 	"github.com/spf13/viper"
 	"log"
+	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -63,9 +72,55 @@ func main() {
 	middL := _linkHttpMiddleware.InitMiddleware()
 	e.Use(middL.CORS)
 
-	linkRepo := _linkRepo.NewMysqlLinkRepository(dbConn)
+	reservedAliases := reserved.New()
+	reservedAliases.Reserve(viper.GetStringSlice("alias.reserved")...)
+	if path := viper.GetString("alias.blocklist"); path != "" {
+		err = reservedAliases.LoadBlocklistFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	secureGenerator, err := random.NewSecureGenerator(viper.GetString("alias.alphabet"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	allowedGenerator := random.NewSkippingGenerator(secureGenerator, reservedAliases.IsReserved)
+
+	var aliasGenerator random.AliasGenerator = random.NewGrowingGenerator(allowedGenerator,
+		viper.GetInt("alias.grow_window"), viper.GetFloat64("alias.grow_threshold"), viper.GetInt("alias.max_growth"))
+
 	timeOutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
-	lu := usecase.NewLinkUseCase(linkRepo, timeOutContext)
+
+	var idAliases random.IdAliasGenerator
+	var aliasKeyPool domain.AliasKeyPool
+	switch viper.GetString("alias.strategy") {
+	case "sequential":
+		idAliases, err = random.NewFeistelGenerator([]byte(viper.GetString("alias.secret")),
+			viper.GetString("alias.alphabet"), reservedAliases.IsReserved)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "pool":
+		poolLength := viper.GetInt("alias.pool.length")
+		if poolLength == 0 {
+			poolLength = viper.GetInt("alias_length")
+		}
+
+		aliasKeyPool = usecase.NewAliasKeyPool(_linkRepo.NewMysqlAliasKeysRepository(dbConn), allowedGenerator,
+			aliasGenerator, usecase.AliasKeyPoolConfig{
+				Length:         poolLength,
+				ClaimSize:      viper.GetInt("alias.pool.claim_size"),
+				LowWater:       viper.GetInt64("alias.pool.low_water"),
+				RefillSize:     viper.GetInt("alias.pool.refill_size"),
+				RefillInterval: time.Duration(viper.GetInt("alias.pool.refill_interval")) * time.Second,
+			}, timeOutContext)
+		aliasGenerator = aliasKeyPool
+	}
+
+	linkRepo := _linkRepo.NewMysqlLinkRepository(dbConn)
+	lu := usecase.NewLinkUseCase(linkRepo, aliasGenerator, idAliases, timeOutContext)
 
 	linkTagRepo := _linkRepo.NewMysqlLinkTagRepository(dbConn)
 	linkTagUcase := usecase.NewLinkTagUseCase(linkTagRepo, timeOutContext)
@@ -73,7 +128,168 @@ func main() {
 	tagsRepo := _linkRepo.NewMysqlTagsRepository(dbConn)
 	tagsUcase := usecase.NewTagsUseCase(tagsRepo, timeOutContext)
 
-	_linkHttpDelivery.NewLinkHandler(e, lu, tagsUcase, linkTagUcase)
+	visitsRepo := _linkRepo.NewMysqlVisitsRepository(dbConn)
+	visitsUcase := usecase.NewVisitsUseCase(visitsRepo, timeOutContext)
+
+	userAgentsRepo := _linkRepo.NewMysqlUserAgentsRepository(dbConn)
+	browsersRepo := _linkRepo.NewMysqlBrowsersRepository(dbConn)
+	devicesRepo := _linkRepo.NewMysqlDevicesRepository(dbConn)
+	userAgentsUcase := usecase.NewUserAgentsUseCase(userAgentsRepo, browsersRepo, devicesRepo, timeOutContext)
+
+	referrersRepo := _linkRepo.NewMysqlReferrersRepository(dbConn)
+	referrersUcase := usecase.NewReferrersUseCase(referrersRepo, viper.GetBool("referrer.strip_query"), timeOutContext)
+
+	visitsWriter := usecase.NewVisitsWriter(visitsRepo, usecase.VisitsWriterConfig{
+		QueueSize:      viper.GetInt("visits.queue_size"),
+		Workers:        viper.GetInt("visits.workers"),
+		BatchSize:      viper.GetInt("visits.batch_size"),
+		FlushInterval:  time.Duration(viper.GetInt("visits.flush_interval")) * time.Millisecond,
+		EnqueueTimeout: time.Duration(viper.GetInt("visits.enqueue_timeout")) * time.Millisecond,
+	}, timeOutContext, userAgentsUcase, referrersUcase)
+
+	var geo *geoip.DB
+	if path := viper.GetString("geoip.database"); path != "" {
+		geo, err = geoip.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	rulesRepo := _linkRepo.NewMysqlRedirectRulesRepository(dbConn)
+	rulesUcase := usecase.NewRedirectRulesUseCase(rulesRepo, geo, timeOutContext)
+
+	variantsRepo := _linkRepo.NewMysqlLinkVariantsRepository(dbConn)
+	variantsUcase := usecase.NewLinkVariantsUseCase(variantsRepo, timeOutContext)
+
+	domainsRepo := _linkRepo.NewMysqlDomainsRepository(dbConn)
+	domainsUcase := usecase.NewDomainsUseCase(domainsRepo, timeOutContext)
+
+	idempotencyRepo := _linkRepo.NewMysqlIdempotencyKeysRepository(dbConn)
+	idempotencyUcase := usecase.NewIdempotencyUseCase(idempotencyRepo,
+		time.Duration(viper.GetInt("idempotency.ttl"))*time.Second,
+		time.Duration(viper.GetInt("idempotency.lease"))*time.Second, timeOutContext)
+
+	linkImportsRepo := _linkRepo.NewMysqlLinkImportsRepository(dbConn)
+	linkImporter := usecase.NewLinkImporter(linkImportsRepo, lu, tagsUcase, usecase.LinkImporterConfig{
+		QueueSize: viper.GetInt("import.queue_size"),
+		Workers:   viper.GetInt("import.workers"),
+		BatchSize: viper.GetInt("import.batch_size"),
+	}, timeOutContext)
+
+	_linkHttpDelivery.NewLinkHandler(e, lu, tagsUcase, linkTagUcase, visitsUcase, visitsWriter, referrersUcase, rulesUcase,
+		variantsUcase, domainsUcase, reservedAliases, idempotencyUcase, linkImporter)
+	_linkHttpDelivery.NewDeepLinkHandler(e, readFile(viper.GetString("deeplink.apple_app_site_association")),
+		readFile(viper.GetString("deeplink.assetlinks")))
+	_linkHttpDelivery.NewRedirectRuleHandler(e, rulesUcase, lu)
+	_linkHttpDelivery.NewDomainHandler(e, domainsUcase)
+	_linkHttpDelivery.ReserveRoutes(e, reservedAliases)
+
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+
+	if days := viper.GetInt("trash.retention_days"); days > 0 {
+		go purgeTrash(purgeCtx, lu, time.Duration(days)*24*time.Hour,
+			time.Duration(viper.GetInt("trash.purge_interval"))*time.Second)
+	}
+
+	go purgeIdempotencyKeys(purgeCtx, idempotencyUcase, time.Duration(viper.GetInt("idempotency.purge_interval"))*time.Second)
+
+	go func() {
+		err := e.Start(viper.GetString("server.address"))
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	stopPurge()
+
+	// every component gets its own deadline, so a slow one doesn't cut the others short.
+	// Visits are drained before the importer, which may take long to finish its running import.
+	closeWithin(10*time.Second, e.Shutdown)
+
+	if aliasKeyPool != nil {
+		closeWithin(10*time.Second, aliasKeyPool.Close)
+	}
+
+	closeWithin(10*time.Second, visitsWriter.Close)
+	closeWithin(10*time.Second, linkImporter.Close)
+
+	log.Printf("visits writer stopped: %+v", visitsWriter.Stats())
+}
+
+// closeWithin calls fn with a context which is done after timeout and logs its error
+func closeWithin(timeout time.Duration, fn func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := fn(ctx); err != nil {
+		log.Println(err)
+	}
+}
+
+// purgeTrash hard deletes the links which stayed in the trash longer than the retention period, until ctx is done
+func purgeTrash(ctx context.Context, lu domain.LinkUseCase, retention time.Duration, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := lu.Purge(ctx, retention)
+		if err != nil {
+			log.Println(err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted links", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeIdempotencyKeys deletes the expired idempotency keys, until ctx is done
+func purgeIdempotencyKeys(ctx context.Context, iu domain.IdempotencyUseCase, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := iu.Purge(ctx)
+		if err != nil {
+			log.Println(err)
+		} else if purged > 0 {
+			log.Printf("purged %d expired idempotency keys", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readFile returns content of the optional file, an empty path means the file is not configured
+func readFile(path string) []byte {
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	log.Fatal(e.Start(viper.GetString("server.address"))) //nolint
+	return content
 }
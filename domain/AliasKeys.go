@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"context"
+)
+
+// AliasKeyPool hands out pre-generated aliases, it has the same contract as random.AliasGenerator
+type AliasKeyPool interface {
+	Generate(length int) (string, error)
+	// Close stops the background refiller
+	Close(ctx context.Context) error
+}
+
+// AliasKeysRepository represent the pre-generated alias's repository contract
+type AliasKeysRepository interface {
+	// Claim marks up to n unused aliases as claimed and returns them, concurrent claims never get the same alias
+	Claim(ctx context.Context, n int) ([]string, error)
+	// Insert adds the aliases to the pool, aliases which were ever in the pool or are used by links are skipped
+	Insert(ctx context.Context, aliases []string) (int64, error)
+	CountUnclaimed(ctx context.Context) (int64, error)
+}
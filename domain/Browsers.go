@@ -1,10 +1,21 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Browsers struct {
 	ID        int64     `json:"id" db:"id"`
 	Name      string    `json:"name" db:"name"`
+	Version   string    `json:"version" db:"version"`
 	CreatedAt time.Time `json:"-" db:"created_at"`
 	UpdatedAt time.Time `json:"-" db:"updated_at"`
 }
+
+// BrowsersRepository represent the browser's repository contract
+type BrowsersRepository interface {
+	GetById(ctx context.Context, id int64) (Browsers, error)
+	GetByNameAndVersion(ctx context.Context, name string, version string) (Browsers, error)
+	FirstOrCreate(ctx context.Context, browser Browsers) (int64, error)
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Devices struct {
 	ID        int64     `json:"id" db:"id"`
@@ -8,3 +11,10 @@ type Devices struct {
 	CreatedAt time.Time `json:"-" db:"created_at"`
 	UpdatedAt time.Time `json:"-" db:"updated_at"`
 }
+
+// DevicesRepository represent the device's repository contract
+type DevicesRepository interface {
+	GetById(ctx context.Context, id int64) (Devices, error)
+	GetByName(ctx context.Context, name string) (Devices, error)
+	FirstOrCreate(ctx context.Context, device Devices) (int64, error)
+}
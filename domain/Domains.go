@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Domain is representing a short domain, every domain has its own alias namespace.
+// Links of the primary host, which is not stored, have domain id 0.
+type Domain struct {
+	ID   int64  `json:"id" db:"id"`
+	Host string `json:"host" db:"host"`
+	// DefaultRedirect is where the domain's root and unknown aliases are sent
+	DefaultRedirect string `json:"default_redirect,omitempty" db:"default_redirect"`
+	// NotFoundPage is the HTML answered for unknown aliases, it wins over DefaultRedirect
+	NotFoundPage string `json:"not_found_page,omitempty" db:"not_found_page"`
+	// AliasLength is the length of generated aliases, 0 means the server default
+	AliasLength int `json:"alias_length,omitempty" db:"alias_length"`
+	// RedirectStatus is the HTTP status of the redirects of links without their own, 0 means the server default
+	RedirectStatus int       `json:"redirect_status,omitempty" db:"redirect_status"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"-" db:"updated_at"`
+}
+
+type DomainRequest struct {
+	Host            string `json:"host" validate:"required,hostname"`
+	DefaultRedirect string `json:"default_redirect,omitempty" validate:"omitempty,weburl"`
+	NotFoundPage    string `json:"not_found_page,omitempty"`
+	AliasLength     int    `json:"alias_length,omitempty" validate:"omitempty,gte=3,lte=10"`
+	RedirectStatus  int    `json:"redirect_status,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+}
+
+// DomainsUseCase represent the domain's use-cases
+type DomainsUseCase interface {
+	Fetch(ctx context.Context, limit int64) ([]Domain, error)
+	GetById(ctx context.Context, id int64) (Domain, error)
+	GetByHost(ctx context.Context, host string) (Domain, error)
+	Update(ctx context.Context, domain Domain) (int64, error)
+	Store(ctx context.Context, domain Domain) (int64, error)
+	Delete(ctx context.Context, id int64) error
+	// Resolve returns the domain of the request's Host, unknown hosts resolve to the primary one
+	Resolve(ctx context.Context, host string) (Domain, error)
+}
+
+// DomainsRepository represent the domain's repository contract
+type DomainsRepository interface {
+	Fetch(ctx context.Context, limit int64) ([]Domain, error)
+	GetById(ctx context.Context, id int64) (Domain, error)
+	GetByHost(ctx context.Context, host string) (Domain, error)
+	Update(ctx context.Context, domain Domain) (int64, error)
+	Store(ctx context.Context, domain Domain) (int64, error)
+	Delete(ctx context.Context, id int64) error
+}
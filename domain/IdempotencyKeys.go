@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyKey is representing a request made with the Idempotency-Key header and its response.
+// StatusCode is 0 while the first request is still running, ExpiresAt is then the end of its short lease.
+// Owner is the random token of the reservation, only its holder can complete or release the key.
+type IdempotencyKey struct {
+	Key         string    `json:"key" db:"idempotency_key"`
+	Fingerprint string    `json:"-" db:"fingerprint"`
+	Owner       string    `json:"-" db:"owner"`
+	StatusCode  int       `json:"status_code" db:"status_code"`
+	Response    []byte    `json:"-" db:"response"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// IdempotencyUseCase represent the idempotency key's use-cases
+type IdempotencyUseCase interface {
+	// Begin reserves the key for the request and returns the reservation. A completed request with the same
+	// fingerprint is returned to be replayed, a different fingerprint is ErrIdempotencyKeyReused and a running
+	// one ErrIdempotencyInProgress. A request whose lease ran out without completing is taken over.
+	Begin(ctx context.Context, key string, fingerprint string) (IdempotencyKey, bool, error)
+	// Complete stores the response of the reservation, it fails when the reservation was taken over
+	Complete(ctx context.Context, reservation IdempotencyKey, statusCode int, response []byte) error
+	// Release forgets the reservation, so the request can be retried
+	Release(ctx context.Context, reservation IdempotencyKey) error
+	Purge(ctx context.Context) (int64, error)
+}
+
+// IdempotencyKeysRepository represent the idempotency key's repository contract
+type IdempotencyKeysRepository interface {
+	GetByKey(ctx context.Context, key string) (IdempotencyKey, error)
+	// Reserve stores the pending key, it returns ErrConflict when the key exists and is not expired
+	Reserve(ctx context.Context, key IdempotencyKey) error
+	// Complete stores the response of the pending key reserved by owner, which is then kept until expiresAt
+	Complete(ctx context.Context, key string, owner string, statusCode int, response []byte, expiresAt time.Time) error
+	// Delete removes the key reserved by owner
+	Delete(ctx context.Context, key string, owner string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
@@ -3,6 +3,8 @@ package domain
 import (
 	"context"
 	"database/sql"
+	"github.com/iambakhodir/short-link/domain/useragent"
+	"net/url"
 	"time"
 )
 
@@ -10,47 +12,381 @@ import (
 type Link struct {
 	ID          int64          `json:"id" db:"id"`
 	UserId      int64          `json:"user_id,omitempty" db:"user_id"`
+	DomainId    int64          `json:"domain_id,omitempty" db:"domain_id"`
 	Alias       string         `json:"alias,omitempty" db:"alias"`
 	Target      string         `json:"target" validate:"required" db:"target"`
+	TargetHash  string         `json:"-" db:"target_hash"`
 	Description sql.NullString `json:"description,omitempty" validate:"max=512" db:"description"`
-	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time      `json:"-" db:"updated_at"`
-	DeletedAt   sql.NullTime   `json:"-" db:"deleted_at"`
+	// RedirectType is the HTTP status of the redirect, 0 means the server default
+	RedirectType int           `json:"redirect_type,omitempty" db:"redirect_type"`
+	ExpiresAt    sql.NullTime  `json:"expires_at,omitempty" db:"expires_at"`
+	ActivateAt   sql.NullTime  `json:"activate_at,omitempty" db:"activate_at"`
+	DeactivateAt sql.NullTime  `json:"deactivate_at,omitempty" db:"deactivate_at"`
+	MaxVisits    sql.NullInt64 `json:"max_visits,omitempty" db:"max_visits"`
+	VisitsCount  int64         `json:"visits_count" db:"visits_count"`
+	// PasswordHash is the bcrypt hash of the link's password, Password is only set before the link is stored
+	PasswordHash sql.NullString `json:"-" db:"password_hash"`
+	Password     string         `json:"-" db:"-"`
+	// IosUrl and AndroidUrl are app deep links, store urls are used when the app is not installed
+	IosUrl          sql.NullString `json:"ios_url,omitempty" db:"ios_url"`
+	IosStoreUrl     sql.NullString `json:"ios_store_url,omitempty" db:"ios_store_url"`
+	AndroidUrl      sql.NullString `json:"android_url,omitempty" db:"android_url"`
+	AndroidStoreUrl sql.NullString `json:"android_store_url,omitempty" db:"android_store_url"`
+	// QueryPolicy decides which visitor's query parameters are forwarded, empty means the server default
+	QueryPolicy    string         `json:"query_policy,omitempty" db:"query_policy"`
+	QueryAllowlist sql.NullString `json:"query_allowlist,omitempty" db:"query_allowlist"`
+	// UtmParams are the encoded default UTM parameters merged into the target
+	UtmParams sql.NullString `json:"utm_params,omitempty" db:"utm_params"`
+	// ForcePreview shows the preview page on every visit instead of redirecting
+	ForcePreview bool `json:"force_preview,omitempty" db:"force_preview"`
+	// Status is either LinkStatusActive or LinkStatusDisabled, see LifecycleStatus for the effective one
+	Status    string       `json:"status" db:"status"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time    `json:"-" db:"updated_at"`
+	DeletedAt sql.NullTime `json:"-" db:"deleted_at"`
+	// Variants are the weighted A/B targets, they are stored in link_variants
+	Variants []LinkVariant `json:"variants,omitempty" db:"-"`
 }
 
 type LinkRequest struct {
-	Target      string   `json:"target" validate:"required,url"`
-	Alias       string   `json:"alias,omitempty"`
-	Length      int      `json:"length,omitempty" validate:"omitempty,gte=3,lte=10"`
-	Description string   `json:"description,omitempty" validate:"max=512"`
+	Target          string               `json:"target" validate:"required,weburl"`
+	Alias           string               `json:"alias,omitempty" validate:"omitempty,alias"`
+	Length          int                  `json:"length,omitempty" validate:"omitempty,gte=3,lte=10"`
+	Domain          string               `json:"domain,omitempty" validate:"omitempty,hostname"`
+	Description     string               `json:"description,omitempty" validate:"max=512"`
+	Tags            []string             `json:"tags,omitempty" validate:"dive,required"`
+	RedirectType    int                  `json:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	ExpiresAt       *time.Time           `json:"expires_at,omitempty"`
+	MaxVisits       int64                `json:"max_visits,omitempty" validate:"omitempty,gte=1"`
+	ActivateAt      *time.Time           `json:"activate_at,omitempty"`
+	DeactivateAt    *time.Time           `json:"deactivate_at,omitempty"`
+	Password        string               `json:"password,omitempty" validate:"omitempty,min=4,max=72"`
+	Variants        []LinkVariantRequest `json:"variants,omitempty" validate:"omitempty,dive"`
+	IosUrl          string               `json:"ios_url,omitempty" validate:"omitempty,appurl"`
+	IosStoreUrl     string               `json:"ios_store_url,omitempty" validate:"omitempty,weburl"`
+	AndroidUrl      string               `json:"android_url,omitempty" validate:"omitempty,appurl"`
+	AndroidStoreUrl string               `json:"android_store_url,omitempty" validate:"omitempty,weburl"`
+	QueryPolicy     string               `json:"query_policy,omitempty" validate:"omitempty,oneof=all allowlist none"`
+	QueryAllowlist  []string             `json:"query_allowlist,omitempty" validate:"dive,required"`
+	Utm             *LinkUtm             `json:"utm,omitempty"`
+	ForcePreview    bool                 `json:"force_preview,omitempty"`
+	Dedupe          *bool                `json:"dedupe,omitempty"`
+}
+
+// LinkPatchRequest is representing the partial update of the link, absent fields are kept.
+// Tags replaces the link's tags, AddTags and RemoveTags are applied after it.
+type LinkPatchRequest struct {
+	Target      *string  `json:"target,omitempty" validate:"omitempty,weburl"`
+	Alias       *string  `json:"alias,omitempty" validate:"omitempty,alias"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=512"`
 	Tags        []string `json:"tags,omitempty" validate:"dive,required"`
+	AddTags     []string `json:"add_tags,omitempty" validate:"dive,required"`
+	RemoveTags  []string `json:"remove_tags,omitempty" validate:"dive,required"`
+}
+
+// LinkUtm is representing the default UTM parameters of the link
+type LinkUtm struct {
+	Source   string `json:"source,omitempty" validate:"max=128"`
+	Medium   string `json:"medium,omitempty" validate:"max=128"`
+	Campaign string `json:"campaign,omitempty" validate:"max=128"`
+	Term     string `json:"term,omitempty" validate:"max=128"`
+	Content  string `json:"content,omitempty" validate:"max=128"`
 }
 
 type LinkResponse struct {
-	ID          int64     `json:"id"`
-	Target      string    `json:"target"`
-	Alias       string    `json:"alias,omitempty"`
-	Description string    `json:"description,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	Tags        []Tags    `json:"tags,omitempty"`
+	ID              int64         `json:"id"`
+	DomainId        int64         `json:"domain_id,omitempty"`
+	Target          string        `json:"target"`
+	Alias           string        `json:"alias,omitempty"`
+	Description     string        `json:"description,omitempty"`
+	RedirectType    int           `json:"redirect_type,omitempty"`
+	ExpiresAt       *time.Time    `json:"expires_at,omitempty"`
+	MaxVisits       int64         `json:"max_visits,omitempty"`
+	ActivateAt      *time.Time    `json:"activate_at,omitempty"`
+	DeactivateAt    *time.Time    `json:"deactivate_at,omitempty"`
+	State           string        `json:"state"`
+	Status          string        `json:"status"`
+	VisitsCount     int64         `json:"visits_count"`
+	Protected       bool          `json:"protected,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	DeletedAt       *time.Time    `json:"deleted_at,omitempty"`
+	Tags            []Tags        `json:"tags,omitempty"`
+	Variants        []LinkVariant `json:"variants,omitempty"`
+	IosUrl          string        `json:"ios_url,omitempty"`
+	IosStoreUrl     string        `json:"ios_store_url,omitempty"`
+	AndroidUrl      string        `json:"android_url,omitempty"`
+	AndroidStoreUrl string        `json:"android_store_url,omitempty"`
+	QueryPolicy     string        `json:"query_policy,omitempty"`
+	QueryAllowlist  []string      `json:"query_allowlist,omitempty"`
+	Utm             *LinkUtm      `json:"utm,omitempty"`
+	ForcePreview    bool          `json:"force_preview,omitempty"`
+}
+
+const (
+	// LinkStateScheduled is a link waiting for its activation date
+	LinkStateScheduled = "scheduled"
+	// LinkStateLive is a link which redirects its visitors
+	LinkStateLive = "live"
+	// LinkStateEnded is a link past its deactivation or expiration date, or its maximum number of visits
+	LinkStateEnded = "ended"
+)
+
+const (
+	// LinkStatusActive is a link which follows its activation window
+	LinkStatusActive = "active"
+	// LinkStatusDisabled is a paused link, it keeps its alias and visits
+	LinkStatusDisabled = "disabled"
+	// LinkStatusExpired is an active link which ended, it is never stored
+	LinkStatusExpired = "expired"
+	// LinkStatusDeleted is a link in the trash, it is never stored
+	LinkStatusDeleted = "deleted"
+)
+
+// LinkBatchItem is representing a link of the bulk creation. The link gets a generated alias of Length
+// when it has none, TagIds are attached to the link.
+type LinkBatchItem struct {
+	Link   Link
+	Length int
+	TagIds []int64
+}
+
+// LinkUpdate is representing the changed link with the ids of all its tags. The variants of the link
+// replace the stored ones only with ReplaceVariants.
+type LinkUpdate struct {
+	Link            Link
+	TagIds          []int64
+	ReplaceVariants bool
+}
+
+// LinkBatchResult is representing the outcome of one item of the bulk creation, Link is stored when Err is nil
+type LinkBatchResult struct {
+	Link Link
+	Err  error
+}
+
+// LinkBulkResult is representing an item of the bulk creation response, Index is its position in the request
+type LinkBulkResult struct {
+	Index int           `json:"index"`
+	Link  *LinkResponse `json:"link,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// LinkFilter is representing the conditions of link listings
+type LinkFilter struct {
+	Limit  int64
+	State  string
+	Status string
+	// Deleted lists the links in the trash instead of the active ones
+	Deleted bool
+}
+
+// IsExpired reports whether the link passed its expiration date or its maximum number of visits
+func (l Link) IsExpired(now time.Time) bool {
+	if l.ExpiresAt.Valid && !now.Before(l.ExpiresAt.Time) {
+		return true
+	}
+
+	return l.MaxVisits.Valid && l.VisitsCount >= l.MaxVisits.Int64
+}
+
+// State returns the state of the link's activation window at the given time
+func (l Link) State(now time.Time) string {
+	if l.IsExpired(now) || (l.DeactivateAt.Valid && !now.Before(l.DeactivateAt.Time)) {
+		return LinkStateEnded
+	}
+
+	if l.ActivateAt.Valid && now.Before(l.ActivateAt.Time) {
+		return LinkStateScheduled
+	}
+
+	return LinkStateLive
+}
+
+// LifecycleStatus returns the effective status of the link, the deleted and expired states win over the stored status
+func (l Link) LifecycleStatus(now time.Time) string {
+	switch {
+	case l.DeletedAt.Valid:
+		return LinkStatusDeleted
+	case l.Status == LinkStatusDisabled:
+		return LinkStatusDisabled
+	case l.State(now) == LinkStateEnded:
+		return LinkStatusExpired
+	default:
+		return LinkStatusActive
+	}
+}
+
+// IsValidAlias reports whether alias only has letters, digits, dashes and underscores, other characters
+// clash with the preview suffix and the temporary aliases or make the alias unreachable in the path
+func IsValidAlias(alias string) bool {
+	if alias == "" {
+		return false
+	}
+
+	for _, r := range alias {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsWebUrl reports whether raw is an absolute http or https url, the only urls visitors are sent to
+func IsWebUrl(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// IsAppUrl reports whether raw is a deep link, apps may register their own schemes but
+// the schemes running scripts in the browser are rejected
+func IsAppUrl(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	switch u.Scheme {
+	case "", "javascript", "data", "vbscript":
+		return false
+	default:
+		return true
+	}
+}
+
+// SameSettings reports whether the stored settings of the links are the same, the target, alias,
+// password, variants and state of the links are not compared. Times are compared to the second
+// they are stored with.
+func (l Link) SameSettings(other Link) bool {
+	return l.Description == other.Description &&
+		l.RedirectType == other.RedirectType &&
+		sameTime(l.ExpiresAt, other.ExpiresAt) &&
+		sameTime(l.ActivateAt, other.ActivateAt) &&
+		sameTime(l.DeactivateAt, other.DeactivateAt) &&
+		l.MaxVisits == other.MaxVisits &&
+		l.IosUrl == other.IosUrl &&
+		l.IosStoreUrl == other.IosStoreUrl &&
+		l.AndroidUrl == other.AndroidUrl &&
+		l.AndroidStoreUrl == other.AndroidStoreUrl &&
+		l.QueryPolicy == other.QueryPolicy &&
+		l.QueryAllowlist == other.QueryAllowlist &&
+		l.UtmParams == other.UtmParams &&
+		l.ForcePreview == other.ForcePreview
+}
+
+func sameTime(a sql.NullTime, b sql.NullTime) bool {
+	if !a.Valid || !b.Valid {
+		return a.Valid == b.Valid
+	}
+
+	return a.Time.Round(time.Second).Equal(b.Time.Round(time.Second))
+}
+
+// AppLink returns the deep link and the store url of the link for the given platform
+func (l Link) AppLink(os string) (string, string) {
+	switch os {
+	case useragent.OSIOS:
+		return l.IosUrl.String, l.IosStoreUrl.String
+	case useragent.OSAndroid:
+		return l.AndroidUrl.String, l.AndroidStoreUrl.String
+	default:
+		return "", ""
+	}
+}
+
+// Values returns the UTM parameters as query values
+func (u LinkUtm) Values() url.Values {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+		"utm_term":     u.Term,
+		"utm_content":  u.Content,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	return values
+}
+
+// ParseLinkUtm reads the UTM parameters from the encoded query
+func ParseLinkUtm(encoded string) (LinkUtm, error) {
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		return LinkUtm{}, err
+	}
+
+	return LinkUtm{
+		Source:   values.Get("utm_source"),
+		Medium:   values.Get("utm_medium"),
+		Campaign: values.Get("utm_campaign"),
+		Term:     values.Get("utm_term"),
+		Content:  values.Get("utm_content"),
+	}, nil
 }
 
 // LinkUseCase represent the link's use-cases
 type LinkUseCase interface {
-	Fetch(ctx context.Context, limit int64) ([]Link, error)
+	Fetch(ctx context.Context, filter LinkFilter) ([]Link, error)
 	GetById(ctx context.Context, id int64) (Link, error)
 	Update(ctx context.Context, link Link) (int64, error)
-	GetByAlias(ctx context.Context, alias string) (Link, error)
+	// UpdateIfUnmodified stores the changed link with its tags and variants unless it was updated after
+	// unmodifiedSince, which is ErrPreconditionFailed. It returns the link as stored.
+	UpdateIfUnmodified(ctx context.Context, update LinkUpdate, unmodifiedSince time.Time) (Link, error)
+	GetByAlias(ctx context.Context, domainId int64, alias string) (Link, error)
 	Store(ctx context.Context, link Link) (int64, error)
+	// StoreGenerated stores the link under a generated alias, retrying when the alias is taken
+	StoreGenerated(ctx context.Context, link Link, length int) (int64, error)
+	// GetByTarget returns the user's newest active link of the target in the domain
+	GetByTarget(ctx context.Context, userId int64, domainId int64, target string) (Link, error)
+	// StoreBatch stores the links with their variants and tags and reports the result of every item.
+	// In the atomic mode either all links are stored or none, the others fail with ErrBatchAborted.
+	StoreBatch(ctx context.Context, items []LinkBatchItem, atomic bool) ([]LinkBatchResult, error)
+	// CheckBatch reports the errors StoreBatch would give for the links without storing them
+	CheckBatch(ctx context.Context, items []LinkBatchItem) ([]LinkBatchResult, error)
+	// Export calls fn with the pages of the filtered links ordered by id, until fn fails
+	Export(ctx context.Context, filter LinkFilter, fn func(links []Link) error) error
 	Delete(ctx context.Context, id int64) error
+	Hit(ctx context.Context, link Link) error
+	CheckPassword(link Link, password string) error
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+	Enable(ctx context.Context, id int64) error
+	Disable(ctx context.Context, id int64) error
 }
 
 // LinkRepository represent the link's repository contract
 type LinkRepository interface {
-	Fetch(ctx context.Context, limit int64) ([]Link, error)
+	Fetch(ctx context.Context, filter LinkFilter) ([]Link, error)
+	// FetchAfter returns the filtered links with an id greater than afterId ordered by id
+	FetchAfter(ctx context.Context, filter LinkFilter, afterId int64) ([]Link, error)
 	GetById(ctx context.Context, id int64) (Link, error)
 	Update(ctx context.Context, link Link) (int64, error)
-	GetByAlias(ctx context.Context, alias string) (Link, error)
+	// UpdateIfUnmodified updates the link which is not deleted and still has the unmodifiedSince updated_at,
+	// its tags and variants are changed in the same transaction
+	UpdateIfUnmodified(ctx context.Context, update LinkUpdate, unmodifiedSince time.Time) error
+	GetByAlias(ctx context.Context, domainId int64, alias string) (Link, error)
 	Store(ctx context.Context, link Link) (int64, error)
 	Delete(ctx context.Context, id int64) error
+	IncrementVisits(ctx context.Context, id int64) error
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// StoreWithIdAlias stores the link under the alias derived from its new id
+	StoreWithIdAlias(ctx context.Context, link Link, aliasOf func(id int64) (string, error)) (int64, error)
+	GetByTargetHash(ctx context.Context, userId int64, domainId int64, hash string) (Link, error)
+	UpdateStatus(ctx context.Context, id int64, from string, to string) error
+	// TakenAliases reports for every link whether its alias is already used in its domain
+	TakenAliases(ctx context.Context, links []Link) ([]bool, error)
+	// StoreBatch inserts the links with their variants and tags in a single transaction and returns their ids.
+	// Links with an empty alias get the alias derived from their id by aliasOf.
+	StoreBatch(ctx context.Context, items []LinkBatchItem, aliasOf func(id int64) (string, error)) ([]int64, error)
 }
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	// LinkImportPending is an import waiting in the queue
+	LinkImportPending = "pending"
+	// LinkImportRunning is an import which is storing its rows
+	LinkImportRunning = "running"
+	// LinkImportFinished is an import which went through all of its rows, some of them may have failed
+	LinkImportFinished = "finished"
+	// LinkImportFailed is an import which stopped before the end, e.g. on a database error
+	LinkImportFailed = "failed"
+)
+
+// LinkImport is representing the background job of a CSV import. A dry run validates the rows without storing them,
+// its Created counts the rows which would be created.
+type LinkImport struct {
+	ID         int64             `json:"id" db:"id"`
+	Status     string            `json:"status" db:"status"`
+	DryRun     bool              `json:"dry_run" db:"dry_run"`
+	Total      int               `json:"total" db:"total"`
+	Processed  int               `json:"processed" db:"processed"`
+	Created    int               `json:"created" db:"created"`
+	Failed     int               `json:"failed" db:"failed"`
+	Errors     []LinkImportError `json:"errors" db:"errors"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+	FinishedAt sql.NullTime      `json:"-" db:"finished_at"`
+}
+
+// LinkImportError is representing the validation or store error of a row, Row is its line in the file
+type LinkImportError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// LinkImportRow is representing a parsed row of the import. Tags are the names attached to the link,
+// Err is set when the row couldn't be parsed or validated.
+type LinkImportRow struct {
+	Row  int
+	Item LinkBatchItem
+	Tags []string
+	Err  error
+}
+
+// LinkImporter runs the CSV imports in the background
+type LinkImporter interface {
+	// Start queues the import of the rows, it returns ErrQueueFull when too many imports are waiting
+	Start(ctx context.Context, rows []LinkImportRow, dryRun bool) (LinkImport, error)
+	GetById(ctx context.Context, id int64) (LinkImport, error)
+	// Close stops accepting imports and waits until the queued ones are done
+	Close(ctx context.Context) error
+}
+
+// LinkImportsRepository represent the link import's repository contract
+type LinkImportsRepository interface {
+	GetById(ctx context.Context, id int64) (LinkImport, error)
+	Store(ctx context.Context, job LinkImport) (int64, error)
+	Update(ctx context.Context, job LinkImport) error
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LinkVariant is representing one of the weighted targets of an A/B split link
+type LinkVariant struct {
+	ID        int64     `json:"id" db:"id"`
+	LinkId    int64     `json:"link_id" db:"link_id"`
+	Target    string    `json:"target" db:"target"`
+	Weight    int       `json:"weight" db:"weight"`
+	Visits    int64     `json:"visits" db:"-"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+type LinkVariantRequest struct {
+	Target string `json:"target" validate:"required,weburl"`
+	Weight int    `json:"weight" validate:"required,gte=1,lte=1000"`
+}
+
+// LinkVariantsUseCase represent the link variant's use-cases
+type LinkVariantsUseCase interface {
+	FetchByLinkId(ctx context.Context, linkId int64) ([]LinkVariant, error)
+	FetchWithVisitsByLinkId(ctx context.Context, linkId int64) ([]LinkVariant, error)
+	Replace(ctx context.Context, linkId int64, variants []LinkVariant) error
+	Pick(variants []LinkVariant, key string) (LinkVariant, bool)
+}
+
+// LinkVariantsRepository represent the link variant's repository contract
+type LinkVariantsRepository interface {
+	FetchByLinkId(ctx context.Context, linkId int64) ([]LinkVariant, error)
+	FetchWithVisitsByLinkId(ctx context.Context, linkId int64) ([]LinkVariant, error)
+	Replace(ctx context.Context, linkId int64, variants []LinkVariant) error
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// RedirectRule is representing a conditional target of the link, rules are evaluated by position
+type RedirectRule struct {
+	ID        int64         `json:"id" db:"id"`
+	LinkId    int64         `json:"link_id" db:"link_id"`
+	Position  int           `json:"position" db:"position"`
+	Condition RuleCondition `json:"condition" db:"conditions"`
+	Target    string        `json:"target" db:"target"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time     `json:"-" db:"updated_at"`
+}
+
+// RuleCondition matches when every given criterion matches, a criterion matches any of its values
+type RuleCondition struct {
+	Devices     []string    `json:"devices,omitempty" validate:"dive,oneof=desktop mobile tablet bot"`
+	OS          []string    `json:"os,omitempty" validate:"dive,required"`
+	Languages   []string    `json:"languages,omitempty" validate:"dive,required"`
+	Countries   []string    `json:"countries,omitempty" validate:"dive,len=2"`
+	TimeWindow  *TimeWindow `json:"time_window,omitempty"`
+	QueryParams []string    `json:"query_params,omitempty" validate:"dive,required"`
+}
+
+// TimeWindow is a daily window in "15:04" format, From after To means the window crosses midnight
+type TimeWindow struct {
+	From     string `json:"from" validate:"required"`
+	To       string `json:"to" validate:"required"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Value stores the condition as a JSON column
+func (rc RuleCondition) Value() (driver.Value, error) {
+	return json.Marshal(rc)
+}
+
+// Scan reads the condition from a JSON column
+func (rc *RuleCondition) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*rc = RuleCondition{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, rc)
+	case string:
+		return json.Unmarshal([]byte(v), rc)
+	default:
+		return fmt.Errorf("unsupported condition type %T", src)
+	}
+}
+
+type RedirectRuleRequest struct {
+	Position  int           `json:"position"`
+	Condition RuleCondition `json:"condition"`
+	Target    string        `json:"target" validate:"required,weburl"`
+}
+
+// RuleVisitor is representing the request data redirect rules are evaluated against
+type RuleVisitor struct {
+	UserAgent      string
+	AcceptLanguage string
+	IP             net.IP
+	Query          url.Values
+	Time           time.Time
+}
+
+// RedirectRulesUseCase represent the redirect rule's use-cases
+type RedirectRulesUseCase interface {
+	FetchByLinkId(ctx context.Context, linkId int64) ([]RedirectRule, error)
+	GetById(ctx context.Context, id int64) (RedirectRule, error)
+	Update(ctx context.Context, rule RedirectRule) (int64, error)
+	Store(ctx context.Context, rule RedirectRule) (int64, error)
+	Delete(ctx context.Context, id int64) error
+	Match(ctx context.Context, linkId int64, visitor RuleVisitor) (RedirectRule, bool, error)
+}
+
+// RedirectRulesRepository represent the redirect rule's repository contract
+type RedirectRulesRepository interface {
+	FetchByLinkId(ctx context.Context, linkId int64) ([]RedirectRule, error)
+	GetById(ctx context.Context, id int64) (RedirectRule, error)
+	Update(ctx context.Context, rule RedirectRule) (int64, error)
+	Store(ctx context.Context, rule RedirectRule) (int64, error)
+	Delete(ctx context.Context, id int64) error
+}
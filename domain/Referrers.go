@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Referrers struct {
 	ID        int64     `json:"id"`
@@ -9,3 +12,19 @@ type Referrers struct {
 	CreatedAt time.Time `json:"-" db:"created_at"`
 	UpdatedAt time.Time `json:"-" db:"updated_at"`
 }
+
+// ReferrersUseCase represent the referrer's use-cases
+type ReferrersUseCase interface {
+	GetById(ctx context.Context, id int64) (Referrers, error)
+	Resolve(ctx context.Context, raw string) (int64, error)
+	FetchTopByLinkId(ctx context.Context, linkId int64, limit int64) ([]VisitsStat, error)
+	VisitsEnricher
+}
+
+// ReferrersRepository represent the referrer's repository contract
+type ReferrersRepository interface {
+	GetById(ctx context.Context, id int64) (Referrers, error)
+	GetByHash(ctx context.Context, hash string) (Referrers, error)
+	FirstOrCreate(ctx context.Context, referrer Referrers) (int64, error)
+	FetchTopByLinkId(ctx context.Context, linkId int64, limit int64) ([]VisitsStat, error)
+}
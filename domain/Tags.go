@@ -22,6 +22,8 @@ type TagsUseCase interface {
 	FirstOrCreate(ctx context.Context, tags Tags) (int64, error)
 	Delete(ctx context.Context, id int64) error
 	FetchByLinkId(ctx context.Context, linkId int64) ([]Tags, error)
+	// FetchByLinkIds returns the tags of every link by its id
+	FetchByLinkIds(ctx context.Context, linkIds []int64) (map[int64][]Tags, error)
 }
 
 // TagsRepository represent the link's repository contract
@@ -34,4 +36,6 @@ type TagsRepository interface {
 	FirstOrCreate(ctx context.Context, tags Tags) (int64, error)
 	Delete(ctx context.Context, id int64) error
 	FetchByLinkId(ctx context.Context, linkId int64) ([]Tags, error)
+	// FetchByLinkIds returns the tags of every link by its id
+	FetchByLinkIds(ctx context.Context, linkIds []int64) (map[int64][]Tags, error)
 }
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type UserAgents struct {
 	ID        int64     `json:"id"`
@@ -11,3 +14,17 @@ type UserAgents struct {
 	CreatedAt time.Time `json:"-" db:"created_at"`
 	UpdatedAt time.Time `json:"-" db:"updated_at"`
 }
+
+// UserAgentsUseCase represent the user agent's use-cases
+type UserAgentsUseCase interface {
+	GetById(ctx context.Context, id int64) (UserAgents, error)
+	Resolve(ctx context.Context, raw string) (int64, error)
+	VisitsEnricher
+}
+
+// UserAgentsRepository represent the user agent's repository contract
+type UserAgentsRepository interface {
+	GetById(ctx context.Context, id int64) (UserAgents, error)
+	GetByHash(ctx context.Context, hash string) (UserAgents, error)
+	FirstOrCreate(ctx context.Context, userAgent UserAgents) (int64, error)
+}
@@ -13,10 +13,19 @@ type Visits struct {
 	Ip          int       `json:"ip"`
 	Headers     string    `json:"headers"`
 	QueryString string    `json:"query_string"`
+	VariantId   int64     `json:"variant_id,omitempty" db:"variant_id"`
+	UserAgent   string    `json:"-" db:"-"`
+	Referrer    string    `json:"-" db:"-"`
 	CreatedAt   time.Time `json:"-" db:"created_at"`
 	UpdatedAt   time.Time `json:"-" db:"updated_at"`
 }
 
+// VisitsStat is representing the number of visits grouped by name
+type VisitsStat struct {
+	Name   string `json:"name"`
+	Visits int64  `json:"visits"`
+}
+
 // VisitsUseCase represent the link's use-cases
 type VisitsUseCase interface {
 	Fetch(ctx context.Context, limit int64) ([]Visits, error)
@@ -25,6 +34,8 @@ type VisitsUseCase interface {
 	GetByAlias(ctx context.Context, alias string) (Visits, error)
 	Store(ctx context.Context, visit Visits) (int64, error)
 	Delete(ctx context.Context, id int64) error
+	FetchBrowsersStat(ctx context.Context, linkId int64, limit int64) ([]VisitsStat, error)
+	FetchDevicesStat(ctx context.Context, linkId int64, limit int64) ([]VisitsStat, error)
 }
 
 // VisitsRepository represent the visit's repository contract
@@ -35,4 +46,27 @@ type VisitsRepository interface {
 	GetByAlias(ctx context.Context, alias string) (Visits, error)
 	Store(ctx context.Context, visit Visits) (int64, error)
 	Delete(ctx context.Context, id int64) error
+	StoreBatch(ctx context.Context, visits []Visits) error
+	FetchBrowsersStat(ctx context.Context, linkId int64, limit int64) ([]VisitsStat, error)
+	FetchDevicesStat(ctx context.Context, linkId int64, limit int64) ([]VisitsStat, error)
+}
+
+// VisitsEnricher fills the derived fields of a visit before it is stored
+type VisitsEnricher interface {
+	Enrich(ctx context.Context, visit *Visits) error
+}
+
+// VisitsWriterStats represent the counters of the visit's writer
+type VisitsWriterStats struct {
+	Queued  int64 `json:"queued"`
+	Written int64 `json:"written"`
+	Dropped int64 `json:"dropped"`
+	Failed  int64 `json:"failed"`
+}
+
+// VisitsWriter represent the asynchronous visit's writer contract
+type VisitsWriter interface {
+	Write(visit Visits) error
+	Stats() VisitsWriterStats
+	Close(ctx context.Context) error
 }
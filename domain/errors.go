@@ -3,9 +3,22 @@ package domain
 import "errors"
 
 var (
-	ErrInternalServerError = errors.New("Internal Server Error")
-	ErrNotFound            = errors.New("Your requested item is not found")
-	ErrConflict            = errors.New("Your item already exist")
-	ErrBadParamInput       = errors.New("Given param is not valid")
-	ErrLinkIsExists        = errors.New("Link is exists")
+	ErrInternalServerError   = errors.New("Internal Server Error")
+	ErrNotFound              = errors.New("Your requested item is not found")
+	ErrConflict              = errors.New("Your item already exist")
+	ErrBadParamInput         = errors.New("Given param is not valid")
+	ErrLinkIsExists          = errors.New("Link is exists")
+	ErrAliasReserved         = errors.New("Alias is reserved, choose another one")
+	ErrLinkExpired           = errors.New("Link is expired")
+	ErrLinkNotActive         = errors.New("Link is not available yet")
+	ErrLinkDeleted           = errors.New("Link is deleted")
+	ErrLinkDisabled          = errors.New("Link is disabled")
+	ErrInvalidPassword       = errors.New("Password is not valid")
+	ErrTooManyAttempts       = errors.New("Too many attempts, try again later")
+	ErrIdempotencyKeyReused  = errors.New("Idempotency key is already used with another request")
+	ErrIdempotencyInProgress = errors.New("Request with this idempotency key is in progress")
+	ErrPreconditionFailed    = errors.New("Link was modified since it was read, fetch it again")
+	ErrBatchAborted          = errors.New("Link is not created, another link of the batch failed")
+	ErrQueueFull             = errors.New("Queue is full")
+	ErrWriterClosed          = errors.New("Writer is closed")
 )
@@ -0,0 +1,98 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+type ipRange struct {
+	start   net.IP
+	end     net.IP
+	country string
+}
+
+// DB is an in-memory country database loaded from a local CSV file with
+// "range_start,range_end,country_code" rows, the format of the free db-ip and ip2location lite exports.
+type DB struct {
+	ranges []ipRange
+}
+
+// Open loads the CSV database from the path
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// Load reads the CSV database from r
+func Load(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	db := &DB{ranges: make([]ipRange, 0)}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 3 {
+			return nil, fmt.Errorf("geoip: line %d: expected 3 columns", line)
+		}
+
+		start := net.ParseIP(strings.TrimSpace(record[0]))
+		end := net.ParseIP(strings.TrimSpace(record[1]))
+		if start == nil || end == nil {
+			// header row or a comment
+			continue
+		}
+
+		db.ranges = append(db.ranges, ipRange{
+			start:   start.To16(),
+			end:     end.To16(),
+			country: strings.ToUpper(strings.TrimSpace(record[2])),
+		})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return bytes.Compare(db.ranges[i].start, db.ranges[j].start) < 0
+	})
+
+	return db, nil
+}
+
+// Country returns ISO 3166 country code of the ip, or an empty string when it is unknown
+func (db *DB) Country(ip net.IP) string {
+	if db == nil || ip == nil {
+		return ""
+	}
+
+	ip = ip.To16()
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return bytes.Compare(db.ranges[i].start, ip) > 0
+	})
+	if i == 0 {
+		return ""
+	}
+
+	r := db.ranges[i-1]
+	if bytes.Compare(ip, r.end) <= 0 {
+		return r.country
+	}
+
+	return ""
+}
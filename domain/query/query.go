@@ -0,0 +1,137 @@
+package query
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+const (
+	PolicyAll       = "all"
+	PolicyAllowlist = "allowlist"
+	PolicyNone      = "none"
+)
+
+// Filter returns the incoming parameters which the policy lets through to the target.
+// Allowlist entries ending with "*" match by prefix, e.g. "utm_*".
+func Filter(incoming url.Values, policy string, allowlist []string) url.Values {
+	result := url.Values{}
+
+	switch policy {
+	case PolicyAll:
+		for key, values := range incoming {
+			result[key] = values
+		}
+	case PolicyAllowlist:
+		for key, values := range incoming {
+			if allowed(key, allowlist) {
+				result[key] = values
+			}
+		}
+	}
+
+	return result
+}
+
+// Merge adds parameters to the target url. When keys conflict, forwarded parameters win
+// over the target's own parameters, which win over the defaults. The target's own query is
+// kept as it is written, e.g. for signed urls, the added parameters are appended to it.
+func Merge(target string, defaults url.Values, forwarded url.Values) (string, error) {
+	if len(defaults) == 0 && len(forwarded) == 0 {
+		return target, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+
+	own := u.Query()
+	added := url.Values{}
+	for key, values := range defaults {
+		if _, ok := own[key]; !ok {
+			added[key] = values
+		}
+	}
+	for key, values := range forwarded {
+		added[key] = values
+	}
+
+	if len(added) == 0 {
+		return target, nil
+	}
+
+	// the target's own parameters overridden by forwarded ones are dropped, the others keep their order
+	kept := make([]string, 0)
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		key := pair
+		if i := strings.IndexByte(key, '='); i >= 0 {
+			key = key[:i]
+		}
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+
+		if _, ok := forwarded[key]; !ok {
+			kept = append(kept, pair)
+		}
+	}
+
+	u.RawQuery = strings.Join(append(kept, added.Encode()), "&")
+
+	return u.String(), nil
+}
+
+// Canonical normalizes the target url so the same destination is written the same way:
+// scheme and host are lowercased, default ports are removed, an empty path becomes "/"
+// and query parameters are sorted. Unparsable targets are returned as they are.
+func Canonical(target string) string {
+	u, err := url.Parse(strings.TrimSpace(target))
+	if err != nil || u.Host == "" {
+		return target
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	// JoinHostPort brings back the brackets of an IPv6 host, which Hostname strips
+	switch {
+	case port != "":
+		host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+
+	return u.String()
+}
+
+func allowed(key string, allowlist []string) bool {
+	for _, item := range allowlist {
+		if strings.HasSuffix(item, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(item, "*")) {
+				return true
+			}
+		} else if key == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,150 @@
+package random
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math"
+)
+
+var (
+	// ErrSkipped is returned by AliasOf when the alias of the id is rejected, the id should not be used
+	ErrSkipped   = errors.New("random: alias of the id was skipped")
+	ErrInvalidId = errors.New("random: id must be positive")
+)
+
+// IdAliasGenerator derives aliases from link ids, the id can be read back from the alias
+type IdAliasGenerator interface {
+	AliasOf(id int64) (string, error)
+	// IdOf returns the id the alias was derived from, false means the alias wasn't generated
+	IdOf(alias string) (int64, bool)
+}
+
+const feistelRounds = 4
+
+// FeistelGenerator permutes the low 32 bits of the id with a keyed Feistel network and encodes the result
+// with its alphabet. Aliases are collision-free, they are as short as the alphabet allows for 32 bits,
+// and consecutive ids give unrelated aliases to whoever doesn't know the secret.
+type FeistelGenerator struct {
+	secret   []byte
+	alphabet []byte
+	index    map[byte]int
+	width    int
+	skip     func(alias string) bool
+}
+
+// NewFeistelGenerator returns the generator for the secret and the alphabet, empty alphabet means Base62Alphabet.
+// Aliases rejected by skip, which can be nil, are never returned.
+func NewFeistelGenerator(secret []byte, alphabet string, skip func(alias string) bool) (*FeistelGenerator, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("random: feistel secret is empty")
+	}
+
+	secure, err := NewSecureGenerator(alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[byte]int, len(secure.alphabet))
+	for i, c := range secure.alphabet {
+		index[c] = i
+	}
+
+	return &FeistelGenerator{
+		secret:   secret,
+		alphabet: secure.alphabet,
+		index:    index,
+		width:    int(math.Ceil(32 / math.Log2(float64(len(secure.alphabet))))),
+		skip:     skip,
+	}, nil
+}
+
+func (g *FeistelGenerator) AliasOf(id int64) (string, error) {
+	if id <= 0 {
+		return "", ErrInvalidId
+	}
+
+	alias := g.encode(uint64(id)>>32<<32 | uint64(g.permute(uint32(id))))
+	if g.skip != nil && g.skip(alias) {
+		return "", ErrSkipped
+	}
+
+	return alias, nil
+}
+
+func (g *FeistelGenerator) IdOf(alias string) (int64, bool) {
+	v, ok := g.decode(alias)
+	if !ok {
+		return 0, false
+	}
+
+	id := int64(v>>32<<32 | uint64(g.unpermute(uint32(v))))
+	if id <= 0 || g.encode(v) != alias {
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (g *FeistelGenerator) permute(x uint32) uint32 {
+	l, r := uint16(x>>16), uint16(x)
+	for i := 0; i < feistelRounds; i++ {
+		l, r = r, l^g.round(i, r)
+	}
+
+	return uint32(l)<<16 | uint32(r)
+}
+
+func (g *FeistelGenerator) unpermute(x uint32) uint32 {
+	l, r := uint16(x>>16), uint16(x)
+	for i := feistelRounds - 1; i >= 0; i-- {
+		l, r = r^g.round(i, l), l
+	}
+
+	return uint32(l)<<16 | uint32(r)
+}
+
+func (g *FeistelGenerator) round(i int, half uint16) uint16 {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte{byte(i), byte(half >> 8), byte(half)})
+	sum := mac.Sum(nil)
+
+	return uint16(sum[0])<<8 | uint16(sum[1])
+}
+
+// encode writes v with the alphabet, left padded to the width of 32 bits
+func (g *FeistelGenerator) encode(v uint64) string {
+	base := uint64(len(g.alphabet))
+
+	b := make([]byte, 0, g.width+2)
+	for v > 0 || len(b) < g.width {
+		b = append(b, g.alphabet[v%base])
+		v /= base
+	}
+
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b)
+}
+
+func (g *FeistelGenerator) decode(alias string) (uint64, bool) {
+	if len(alias) < g.width {
+		return 0, false
+	}
+
+	base := uint64(len(g.alphabet))
+
+	var v uint64
+	for i := 0; i < len(alias); i++ {
+		d, ok := g.index[alias[i]]
+		if !ok || v > (math.MaxInt64-uint64(d))/base {
+			return 0, false
+		}
+
+		v = v*base + uint64(d)
+	}
+
+	return v, true
+}
@@ -0,0 +1,126 @@
+package random
+
+import (
+	"testing"
+)
+
+func newTestFeistel(t *testing.T, skip func(alias string) bool) *FeistelGenerator {
+	g, err := NewFeistelGenerator([]byte("secret"), "", skip)
+	if err != nil {
+		t.Fatalf("NewFeistelGenerator() error = %v", err)
+	}
+
+	return g
+}
+
+func TestFeistelRoundTrip(t *testing.T) {
+	g := newTestFeistel(t, nil)
+
+	ranges := []struct {
+		name  string
+		from  int64
+		count int64
+	}{
+		{"first ids", 1, 100000},
+		{"low bits overflow", 1<<32 - 1000, 2000},
+		{"large ids", 1 << 40, 1000},
+	}
+
+	for _, r := range ranges {
+		t.Run(r.name, func(t *testing.T) {
+			seen := make(map[string]int64, r.count)
+			for id := r.from; id < r.from+r.count; id++ {
+				alias, err := g.AliasOf(id)
+				if err != nil {
+					t.Fatalf("AliasOf(%d) error = %v", id, err)
+				}
+
+				if other, ok := seen[alias]; ok {
+					t.Fatalf("AliasOf(%d) = %q, the alias of %d", id, alias, other)
+				}
+				seen[alias] = id
+
+				got, ok := g.IdOf(alias)
+				if !ok || got != id {
+					t.Fatalf("IdOf(%q) = %d, %v, want %d", alias, got, ok, id)
+				}
+			}
+		})
+	}
+}
+
+func TestFeistelAliasLength(t *testing.T) {
+	g := newTestFeistel(t, nil)
+
+	for _, id := range []int64{1, 2, 1000, 1<<32 - 1} {
+		alias, err := g.AliasOf(id)
+		if err != nil {
+			t.Fatalf("AliasOf(%d) error = %v", id, err)
+		}
+		if len(alias) != g.width {
+			t.Errorf("len(AliasOf(%d)) = %d, want %d", id, len(alias), g.width)
+		}
+	}
+}
+
+func TestFeistelInvalid(t *testing.T) {
+	g := newTestFeistel(t, nil)
+
+	for _, id := range []int64{0, -1} {
+		if _, err := g.AliasOf(id); err != ErrInvalidId {
+			t.Errorf("AliasOf(%d) error = %v, want %v", id, err, ErrInvalidId)
+		}
+	}
+
+	for _, alias := range []string{"", "abc", "ab-cdef", "~abcdef"} {
+		if id, ok := g.IdOf(alias); ok {
+			t.Errorf("IdOf(%q) = %d, want no id", alias, id)
+		}
+	}
+
+	other, err := NewFeistelGenerator([]byte("other secret"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alias, _ := g.AliasOf(42)
+	if id, _ := other.IdOf(alias); id == 42 {
+		t.Errorf("IdOf(%q) with another secret = 42", alias)
+	}
+}
+
+func TestFeistelSkip(t *testing.T) {
+	plain := newTestFeistel(t, nil)
+	reserved, _ := plain.AliasOf(7)
+
+	g := newTestFeistel(t, func(alias string) bool { return alias == reserved })
+
+	if _, err := g.AliasOf(7); err != ErrSkipped {
+		t.Fatalf("AliasOf(7) error = %v, want %v", err, ErrSkipped)
+	}
+
+	for _, id := range []int64{6, 8} {
+		if _, err := g.AliasOf(id); err != nil {
+			t.Errorf("AliasOf(%d) error = %v", id, err)
+		}
+	}
+}
+
+func TestSkippingGeneratorTerminates(t *testing.T) {
+	secure, err := NewSecureGenerator("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	g := NewSkippingGenerator(secure, func(alias string) bool {
+		calls++
+		return true
+	})
+
+	if _, err := g.Generate(6); err != ErrAllSkipped {
+		t.Fatalf("Generate() error = %v, want %v", err, ErrAllSkipped)
+	}
+	if calls != 100 {
+		t.Errorf("skip was called %d times, want 100", calls)
+	}
+}
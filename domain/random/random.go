@@ -1,22 +1,185 @@
 package random
 
 import (
-	"math/rand"
-	"time"
+	"crypto/rand"
+	"errors"
+	"sync"
 )
 
-// NewRandomString generates random string with given size.
-func NewRandomString(size int) string {
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	chars := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+const (
+	// Base62Alphabet is the default alphabet of generated aliases
+	Base62Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
 		"abcdefghijklmnopqrstuvwxyz" +
-		"0123456789")
+		"0123456789"
+	// UnambiguousAlphabet is Base62Alphabet without the characters which are easy to confuse: 0/O, 1/l/I
+	UnambiguousAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ" +
+		"abcdefghijkmnopqrstuvwxyz" +
+		"23456789"
+)
+
+var (
+	// ErrInvalidAlphabet is returned for alphabets with less than 2 or more than 256 characters, or with repeated ones
+	ErrInvalidAlphabet = errors.New("random: alphabet must have 2 to 256 unique characters")
+	ErrInvalidLength   = errors.New("random: length must be positive")
+	// ErrAllSkipped is returned when every generated alias was rejected
+	ErrAllSkipped = errors.New("random: every generated alias was skipped")
+)
+
+// AliasGenerator generates aliases of the given length
+type AliasGenerator interface {
+	Generate(length int) (string, error)
+}
+
+// CollisionObserver is implemented by generators which adapt to taken aliases
+type CollisionObserver interface {
+	// Collided reports that an alias returned by Generate was already taken
+	Collided()
+}
+
+// SecureGenerator picks every character of the alias uniformly from its alphabet using crypto/rand
+type SecureGenerator struct {
+	alphabet []byte
+}
+
+// NewSecureGenerator returns a generator of the single byte characters of the alphabet, empty means Base62Alphabet
+func NewSecureGenerator(alphabet string) (*SecureGenerator, error) {
+	if alphabet == "" {
+		alphabet = Base62Alphabet
+	}
+
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return nil, ErrInvalidAlphabet
+	}
+
+	seen := make(map[byte]bool, len(alphabet))
+	for i := 0; i < len(alphabet); i++ {
+		if seen[alphabet[i]] {
+			return nil, ErrInvalidAlphabet
+		}
+		seen[alphabet[i]] = true
+	}
+
+	return &SecureGenerator{alphabet: []byte(alphabet)}, nil
+}
+
+func (g *SecureGenerator) Generate(length int) (string, error) {
+	if length <= 0 {
+		return "", ErrInvalidLength
+	}
+
+	n := len(g.alphabet)
+	// random bytes at or above limit are skipped, so every character is equally likely
+	limit := 256 - 256%n
+
+	b := make([]byte, 0, length)
+	buf := make([]byte, length+length/2+1)
+	for len(b) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+
+		for _, r := range buf {
+			if int(r) >= limit {
+				continue
+			}
+
+			b = append(b, g.alphabet[int(r)%n])
+			if len(b) == length {
+				break
+			}
+		}
+	}
+
+	return string(b), nil
+}
+
+// GrowingGenerator lengthens the aliases of the wrapped generator by one character every time
+// the share of collisions among the last generated aliases reaches the threshold.
+// The extra length is kept in memory only.
+type GrowingGenerator struct {
+	generator AliasGenerator
+	window    int
+	threshold float64
+	maxGrowth int
+
+	mu         sync.Mutex
+	growth     int
+	generated  int
+	collisions int
+}
+
+// NewGrowingGenerator wraps the generator, window is the number of aliases the collisions are counted for
+// and maxGrowth limits the extra length. Zero values fall back to 100 aliases, 10% and 4 characters.
+func NewGrowingGenerator(generator AliasGenerator, window int, threshold float64, maxGrowth int) *GrowingGenerator {
+	if window <= 0 {
+		window = 100
+	}
+	if threshold <= 0 {
+		threshold = 0.1
+	}
+	if maxGrowth <= 0 {
+		maxGrowth = 4
+	}
+
+	return &GrowingGenerator{generator: generator, window: window, threshold: threshold, maxGrowth: maxGrowth}
+}
+
+func (g *GrowingGenerator) Generate(length int) (string, error) {
+	g.mu.Lock()
+	growth := g.growth
+	g.generated++
+	if g.generated > g.window {
+		g.generated, g.collisions = 1, 0
+	}
+	g.mu.Unlock()
+
+	return g.generator.Generate(length + growth)
+}
+
+func (g *GrowingGenerator) Collided() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.collisions++
+	if float64(g.collisions) >= g.threshold*float64(g.window) && g.growth < g.maxGrowth {
+		g.growth++
+		g.generated, g.collisions = 0, 0
+	}
+}
+
+// SkippingGenerator generates again while the alias is rejected by skip, e.g. when it is reserved
+type SkippingGenerator struct {
+	generator AliasGenerator
+	skip      func(alias string) bool
+}
+
+func NewSkippingGenerator(generator AliasGenerator, skip func(alias string) bool) *SkippingGenerator {
+	return &SkippingGenerator{generator: generator, skip: skip}
+}
+
+func (g *SkippingGenerator) Generate(length int) (string, error) {
+	for i := 0; i < 100; i++ {
+		alias, err := g.generator.Generate(length)
+		if err != nil || !g.skip(alias) {
+			return alias, err
+		}
+	}
+
+	return "", ErrAllSkipped
+}
+
+var defaultGenerator, _ = NewSecureGenerator(Base62Alphabet)
+
+// NewRandomString generates random base62 string with given size.
+func NewRandomString(size int) string {
+	if size <= 0 {
+		return ""
+	}
 
-	b := make([]rune, size)
-	for i := range b {
-		b[i] = chars[rnd.Intn(len(chars))]
+	s, err := defaultGenerator.Generate(size)
+	if err != nil {
+		panic(err)
 	}
 
-	return string(b)
+	return s
 }
@@ -0,0 +1,55 @@
+package referrer
+
+import (
+	"github.com/iambakhodir/short-link/domain/query"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// foldedPrefixes are host prefixes which point to the same site as the bare host
+var foldedPrefixes = []string{"www.", "m.", "mobile."}
+
+// Canonicalize normalizes the Referer header so the same source is always stored once. On top of
+// query.Canonical the user info and the fragment are dropped, www./m. prefixes are removed and,
+// when stripQuery is set, the query string is dropped.
+// It returns false for an empty or unparsable referrer.
+func Canonicalize(raw string, stripQuery bool) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+
+	u, err := url.Parse(query.Canonical(raw))
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	host, port := u.Hostname(), u.Port()
+	for _, prefix := range foldedPrefixes {
+		if strings.HasPrefix(host, prefix) && strings.Count(host, ".") > 1 {
+			host = strings.TrimPrefix(host, prefix)
+			break
+		}
+	}
+
+	// JoinHostPort brings back the brackets of an IPv6 host, which Hostname strips
+	switch {
+	case port != "":
+		host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	if stripQuery {
+		u.RawQuery = ""
+		u.ForceQuery = false
+	}
+
+	return u.String(), true
+}
@@ -0,0 +1,113 @@
+package reserved
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Registry holds the words which can't be used as aliases. Reserved words, like route prefixes,
+// match the whole alias, blocked words match anywhere inside it. Both are case-insensitive.
+type Registry struct {
+	mu      sync.RWMutex
+	words   map[string]struct{}
+	blocked []string
+}
+
+func New() *Registry {
+	return &Registry{words: make(map[string]struct{})}
+}
+
+// Reserve adds the words which must not be used as a whole alias
+func (r *Registry) Reserve(words ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w != "" {
+			r.words[w] = struct{}{}
+		}
+	}
+}
+
+// Block adds the words which must not appear anywhere inside an alias
+func (r *Registry) Block(words ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w != "" {
+			r.blocked = append(r.blocked, w)
+		}
+	}
+}
+
+// ReserveRoutes reserves the first static segment of every route path, "/links/:id" reserves "links"
+func (r *Registry) ReserveRoutes(paths ...string) {
+	for _, p := range paths {
+		segment := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)[0]
+		if segment == "" || strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			continue
+		}
+
+		r.Reserve(segment)
+	}
+}
+
+// LoadBlocklist blocks the words read from r, one per line. Empty lines and lines starting with # are skipped.
+func (r *Registry) LoadBlocklist(reader io.Reader) error {
+	words := make([]string, 0)
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		words = append(words, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	r.Block(words...)
+
+	return nil
+}
+
+// LoadBlocklistFile blocks the words of the file at path, see LoadBlocklist
+func (r *Registry) LoadBlocklistFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.LoadBlocklist(f)
+}
+
+// IsReserved reports whether the alias is a reserved word or contains a blocked one
+func (r *Registry) IsReserved(alias string) bool {
+	alias = strings.ToLower(alias)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.words[alias]; ok {
+		return true
+	}
+
+	for _, w := range r.blocked {
+		if strings.Contains(alias, w) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package rules
+
+import (
+	"errors"
+	"github.com/iambakhodir/short-link/domain"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTimeWindow = errors.New("Time window is not valid")
+
+// Request is representing the visitor's attributes rules are matched against
+type Request struct {
+	Device    string
+	OS        string
+	Languages []string
+	Country   string
+	Time      time.Time
+	Query     url.Values
+}
+
+// Evaluate returns the first rule in position order whose condition matches the request
+func Evaluate(rules []domain.RedirectRule, req Request) (domain.RedirectRule, bool) {
+	sorted := make([]domain.RedirectRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	for _, rule := range sorted {
+		if Match(rule.Condition, req) {
+			return rule, true
+		}
+	}
+
+	return domain.RedirectRule{}, false
+}
+
+// Match reports whether every criterion of the condition matches the request
+func Match(cond domain.RuleCondition, req Request) bool {
+	if len(cond.Devices) > 0 && !containsFold(cond.Devices, req.Device) {
+		return false
+	}
+
+	if len(cond.OS) > 0 && !containsFold(cond.OS, req.OS) {
+		return false
+	}
+
+	if len(cond.Countries) > 0 && !containsFold(cond.Countries, req.Country) {
+		return false
+	}
+
+	if len(cond.Languages) > 0 && !matchLanguage(cond.Languages, req.Languages) {
+		return false
+	}
+
+	if len(cond.QueryParams) > 0 && !matchQuery(cond.QueryParams, req.Query) {
+		return false
+	}
+
+	if cond.TimeWindow != nil {
+		in, err := InWindow(*cond.TimeWindow, req.Time)
+		if err != nil || !in {
+			return false
+		}
+	}
+
+	return true
+}
+
+// InWindow reports whether t is inside the daily window. A window whose From is
+// after To crosses midnight, e.g. 22:00-06:00.
+func InWindow(window domain.TimeWindow, t time.Time) (bool, error) {
+	from, err := parseClock(window.From)
+	if err != nil {
+		return false, err
+	}
+
+	to, err := parseClock(window.To)
+	if err != nil {
+		return false, err
+	}
+
+	if window.Timezone != "" {
+		loc, err := time.LoadLocation(window.Timezone)
+		if err != nil {
+			return false, ErrInvalidTimeWindow
+		}
+		t = t.In(loc)
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	if from <= to {
+		return now >= from && now < to, nil
+	}
+
+	return now >= from || now < to, nil
+}
+
+// ValidateTimeWindow checks clock format and time zone of the window
+func ValidateTimeWindow(window domain.TimeWindow) error {
+	_, err := InWindow(window, time.Now())
+	return err
+}
+
+// ParseAcceptLanguage returns language tags of the Accept-Language header ordered by quality
+func ParseAcceptLanguage(header string) []string {
+	type lang struct {
+		tag     string
+		quality float64
+	}
+
+	langs := make([]lang, 0)
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		tag, quality := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			tag = strings.TrimSpace(part[:i])
+			params := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(params, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if tag == "" || tag == "*" || quality <= 0 {
+			continue
+		}
+		langs = append(langs, lang{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].quality > langs[j].quality
+	})
+
+	result := make([]string, 0, len(langs))
+	for _, l := range langs {
+		result = append(result, l.tag)
+	}
+
+	return result
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, ErrInvalidTimeWindow
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchLanguage matches "en" against "en-US" as well, but "en-US" only against "en-US"
+func matchLanguage(wanted []string, accepted []string) bool {
+	for _, a := range accepted {
+		for _, w := range wanted {
+			if strings.EqualFold(a, w) {
+				return true
+			}
+			if len(a) > len(w) && a[len(w)] == '-' && strings.EqualFold(a[:len(w)], w) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchQuery(params []string, query url.Values) bool {
+	for _, p := range params {
+		if _, ok := query[p]; ok {
+			return true
+		}
+	}
+
+	return false
+}
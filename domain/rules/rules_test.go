@@ -0,0 +1,136 @@
+package rules
+
+import (
+	"github.com/iambakhodir/short-link/domain"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	noon := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	req := Request{
+		Device:    "mobile",
+		OS:        "iOS",
+		Languages: []string{"en-US", "ru"},
+		Country:   "UZ",
+		Time:      noon,
+		Query:     url.Values{"ref": {"mail"}},
+	}
+
+	tests := []struct {
+		name string
+		cond domain.RuleCondition
+		want bool
+	}{
+		{"empty condition", domain.RuleCondition{}, true},
+		{"device", domain.RuleCondition{Devices: []string{"desktop", "Mobile"}}, true},
+		{"other device", domain.RuleCondition{Devices: []string{"desktop"}}, false},
+		{"os", domain.RuleCondition{OS: []string{"ios"}}, true},
+		{"other os", domain.RuleCondition{OS: []string{"Android"}}, false},
+		{"country", domain.RuleCondition{Countries: []string{"uz", "KZ"}}, true},
+		{"other country", domain.RuleCondition{Countries: []string{"US"}}, false},
+		{"language prefix", domain.RuleCondition{Languages: []string{"en"}}, true},
+		{"exact language", domain.RuleCondition{Languages: []string{"RU"}}, true},
+		{"other region", domain.RuleCondition{Languages: []string{"en-GB"}}, false},
+		{"query param", domain.RuleCondition{QueryParams: []string{"utm_source", "ref"}}, true},
+		{"missing query param", domain.RuleCondition{QueryParams: []string{"utm_source"}}, false},
+		{"inside time window", domain.RuleCondition{TimeWindow: &domain.TimeWindow{From: "09:00", To: "18:00"}}, true},
+		{"outside time window", domain.RuleCondition{TimeWindow: &domain.TimeWindow{From: "13:00", To: "18:00"}}, false},
+		{"invalid time window", domain.RuleCondition{TimeWindow: &domain.TimeWindow{From: "9am", To: "18:00"}}, false},
+		{"all criteria", domain.RuleCondition{Devices: []string{"mobile"}, Countries: []string{"UZ"}, Languages: []string{"ru"}}, true},
+		{"one criterion fails", domain.RuleCondition{Devices: []string{"mobile"}, Countries: []string{"US"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.cond, req); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		window  domain.TimeWindow
+		t       time.Time
+		want    bool
+		wantErr bool
+	}{
+		{"start is inside", domain.TimeWindow{From: "09:00", To: "18:00"}, at(9, 0), true, false},
+		{"end is outside", domain.TimeWindow{From: "09:00", To: "18:00"}, at(18, 0), false, false},
+		{"before midnight", domain.TimeWindow{From: "22:00", To: "06:00"}, at(23, 30), true, false},
+		{"after midnight", domain.TimeWindow{From: "22:00", To: "06:00"}, at(5, 59), true, false},
+		{"outside the night", domain.TimeWindow{From: "22:00", To: "06:00"}, at(12, 0), false, false},
+		{"time zone", domain.TimeWindow{From: "09:00", To: "10:00", Timezone: "Asia/Tashkent"}, at(4, 30), true, false},
+		{"invalid clock", domain.TimeWindow{From: "25:00", To: "06:00"}, at(1, 0), false, true},
+		{"invalid time zone", domain.TimeWindow{From: "09:00", To: "10:00", Timezone: "Nowhere/City"}, at(9, 30), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InWindow(tt.window, tt.t)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("InWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	mobile := domain.RedirectRule{ID: 1, Position: 2, Condition: domain.RuleCondition{Devices: []string{"mobile"}}, Target: "https://m.example.com"}
+	uzbek := domain.RedirectRule{ID: 2, Position: 1, Condition: domain.RuleCondition{Countries: []string{"UZ"}}, Target: "https://uz.example.com"}
+	rules := []domain.RedirectRule{mobile, uzbek}
+
+	tests := []struct {
+		name   string
+		req    Request
+		want   domain.RedirectRule
+		wantOk bool
+	}{
+		{"lower position wins", Request{Device: "mobile", Country: "UZ"}, uzbek, true},
+		{"only the later rule matches", Request{Device: "mobile", Country: "US"}, mobile, true},
+		{"no rule falls through to the link target", Request{Device: "desktop", Country: "US"}, domain.RedirectRule{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Evaluate(rules, tt.req)
+			if ok != tt.wantOk || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, %v, want %v, %v", got.ID, ok, tt.want.ID, tt.wantOk)
+			}
+		})
+	}
+
+	if _, ok := Evaluate(nil, Request{}); ok {
+		t.Error("Evaluate() of no rules matched")
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"ru", []string{"ru"}},
+		{"en;q=0.5, ru, uz;q=0.8", []string{"ru", "uz", "en"}},
+		{"*, de;q=0, fr;q=0.3", []string{"fr"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package useragent
+
+import (
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+
+	OSWindows  = "Windows"
+	OSMacOS    = "macOS"
+	OSIOS      = "iOS"
+	OSAndroid  = "Android"
+	OSLinux    = "Linux"
+	OSChromeOS = "ChromeOS"
+
+	Unknown = "Unknown"
+)
+
+// Info is representing the parsed User-Agent header
+type Info struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	Device         string
+}
+
+type token struct {
+	name   string
+	family string
+}
+
+var botTokens = []token{
+	{"googlebot", "Googlebot"},
+	{"bingbot", "Bingbot"},
+	{"yandexbot", "YandexBot"},
+	{"duckduckbot", "DuckDuckBot"},
+	{"baiduspider", "Baiduspider"},
+	{"facebookexternalhit", "Facebook"},
+	{"twitterbot", "Twitterbot"},
+	{"telegrambot", "TelegramBot"},
+	{"slackbot", "Slackbot"},
+	{"whatsapp", "WhatsApp"},
+	{"curl/", "curl"},
+	{"wget/", "Wget"},
+	{"python-requests", "Python Requests"},
+	{"go-http-client", "Go HTTP Client"},
+	{"bot", "Bot"},
+	{"crawler", "Bot"},
+	{"spider", "Bot"},
+	{"slurp", "Bot"},
+}
+
+// browserTokens are checked in order, since most browsers mention the engines they are based on
+var browserTokens = []token{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Opera/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex Browser"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+	{"MSIE ", "Internet Explorer"},
+	{"rv:", "Internet Explorer"},
+}
+
+// Parse detects browser family and major version, operating system and device class of the given User-Agent
+func Parse(raw string) Info {
+	info := Info{
+		Browser: Unknown,
+		OS:      detectOS(raw),
+		Device:  DeviceDesktop,
+	}
+
+	if raw == "" {
+		info.Device = Unknown
+		return info
+	}
+
+	lower := strings.ToLower(raw)
+	for _, t := range botTokens {
+		if strings.Contains(lower, t.name) {
+			info.Browser = t.family
+			info.Device = DeviceBot
+			return info
+		}
+	}
+
+	for _, t := range browserTokens {
+		if t.name == "rv:" && !strings.Contains(raw, "Trident/") {
+			continue
+		}
+		if t.name == "Version/" && !strings.Contains(raw, "Safari/") {
+			continue
+		}
+
+		if i := strings.Index(raw, t.name); i >= 0 {
+			info.Browser = t.family
+			info.BrowserVersion = majorVersion(raw[i+len(t.name):])
+			break
+		}
+	}
+
+	info.Device = detectDevice(raw)
+
+	return info
+}
+
+func detectOS(raw string) string {
+	switch {
+	case strings.Contains(raw, "Windows"):
+		return OSWindows
+	case strings.Contains(raw, "iPhone"), strings.Contains(raw, "iPad"), strings.Contains(raw, "iPod"):
+		return OSIOS
+	case strings.Contains(raw, "Android"):
+		return OSAndroid
+	case strings.Contains(raw, "CrOS"):
+		return OSChromeOS
+	case strings.Contains(raw, "Mac OS X"), strings.Contains(raw, "Macintosh"):
+		return OSMacOS
+	case strings.Contains(raw, "Linux"):
+		return OSLinux
+	default:
+		return Unknown
+	}
+}
+
+func detectDevice(raw string) string {
+	switch {
+	case strings.Contains(raw, "iPad"), strings.Contains(raw, "Tablet"):
+		return DeviceTablet
+	case strings.Contains(raw, "Android") && !strings.Contains(raw, "Mobile"):
+		return DeviceTablet
+	case strings.Contains(raw, "Mobi"), strings.Contains(raw, "iPhone"), strings.Contains(raw, "iPod"),
+		strings.Contains(raw, "Android"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+func majorVersion(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	return s[:end]
+}
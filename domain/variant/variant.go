@@ -0,0 +1,68 @@
+package variant
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/iambakhodir/short-link/domain"
+	"hash/fnv"
+	"strings"
+)
+
+// Pick chooses a variant by weight. The same key always gets the same variant
+// as long as the variants of the link don't change.
+func Pick(variants []domain.LinkVariant, key string) (domain.LinkVariant, bool) {
+	total := 0
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+
+	if total == 0 {
+		return domain.LinkVariant{}, false
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	point := int(h.Sum64() % uint64(total))
+
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if point < v.Weight {
+			return v, true
+		}
+		point -= v.Weight
+	}
+
+	return domain.LinkVariant{}, false
+}
+
+// Sign appends HMAC signature to the value, so it can be stored in a cookie
+func Sign(secret []byte, value string) string {
+	return value + "." + signature(secret, value)
+}
+
+// Verify returns the value of a signed string when its signature is valid
+func Verify(secret []byte, signed string) (string, bool) {
+	i := strings.LastIndex(signed, ".")
+	if i < 0 {
+		return "", false
+	}
+
+	value := signed[:i]
+	if !hmac.Equal([]byte(signed[i+1:]), []byte(signature(secret, value))) {
+		return "", false
+	}
+
+	return value, true
+}
+
+func signature(secret []byte, value string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/iambakhodir/short-link/domain"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// linkCSVColumns are the columns of the exported links, imports may have them in any order
+var linkCSVColumns = []string{"alias", "target", "description", "tags", "domain", "redirect_type", "expires_at",
+	"max_visits", "activate_at", "deactivate_at"}
+
+const (
+	// csvTagsSeparator separates the tags inside the tags column
+	csvTagsSeparator = "|"
+	// csvQuotedPrefixes start the cells which are exported after a quote: the ones spreadsheets run as
+	// formulas and, so the quote can be told apart on import, the ones starting with a quote
+	csvQuotedPrefixes = "=+-@\t\r'"
+)
+
+// linkCSVRow is a row of the imported file, line is its line in the file
+type linkCSVRow struct {
+	line int
+	req  domain.LinkRequest
+	err  error
+}
+
+// linkCSVRecord returns the columns of the exported link, host is the host of its domain
+func linkCSVRecord(link domain.Link, tags []domain.Tags, host string) []string {
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+
+	redirectType := ""
+	if link.RedirectType > 0 {
+		redirectType = strconv.Itoa(link.RedirectType)
+	}
+
+	maxVisits := ""
+	if link.MaxVisits.Valid {
+		maxVisits = strconv.FormatInt(link.MaxVisits.Int64, 10)
+	}
+
+	record := []string{
+		link.Alias,
+		link.Target,
+		link.Description.String,
+		strings.Join(names, csvTagsSeparator),
+		host,
+		redirectType,
+		csvTime(link.ExpiresAt.Time, link.ExpiresAt.Valid),
+		maxVisits,
+		csvTime(link.ActivateAt.Time, link.ActivateAt.Valid),
+		csvTime(link.DeactivateAt.Time, link.DeactivateAt.Valid),
+	}
+
+	for i, cell := range record {
+		if cell != "" && strings.IndexByte(csvQuotedPrefixes, cell[0]) >= 0 {
+			record[i] = "'" + cell
+		}
+	}
+
+	return record
+}
+
+func csvTime(t time.Time, valid bool) string {
+	if !valid {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// readLinkCSV reads the link requests of the file. The header names the columns and needs the target one,
+// unknown columns are an error. A row which can't be parsed is returned with its error.
+// A cell starting with a quote followed by one of csvQuotedPrefixes loses the quote, as the export adds it,
+// so a hand-made file writes a value starting with a quote and a dash with one more quote in front.
+func readLinkCSV(r io.Reader) ([]linkCSVRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(linkCSVColumns))
+	for _, name := range linkCSVColumns {
+		known[name] = true
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+
+	if _, ok := columns["target"]; !ok {
+		return nil, errors.New("CSV file must have the target column")
+	}
+
+	rows := make([]linkCSVRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+
+		if err != nil {
+			// a malformed row, e.g. with a wrong number of fields, fails alone
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+
+			rows = append(rows, linkCSVRow{line: parseErr.StartLine, err: err})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		req, err := linkCSVRequest(record, columns)
+		rows = append(rows, linkCSVRow{line: line, req: req, err: err})
+	}
+}
+
+// linkCSVRequest returns the link request of the record
+func linkCSVRequest(record []string, columns map[string]int) (domain.LinkRequest, error) {
+	value := func(name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+
+		// the quote the export adds before a formula character or a quote is dropped
+		v := strings.TrimSpace(record[i])
+		if len(v) > 1 && v[0] == '\'' && strings.IndexByte(csvQuotedPrefixes, v[1]) >= 0 {
+			v = v[1:]
+		}
+
+		return v
+	}
+
+	req := domain.LinkRequest{
+		Alias:       value("alias"),
+		Target:      value("target"),
+		Description: value("description"),
+		Domain:      value("domain"),
+	}
+
+	for _, tag := range strings.Split(value("tags"), csvTagsSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			req.Tags = append(req.Tags, tag)
+		}
+	}
+
+	var err error
+	if v := value("redirect_type"); v != "" {
+		if req.RedirectType, err = strconv.Atoi(v); err != nil {
+			return req, fmt.Errorf("redirect_type: %w", err)
+		}
+	}
+
+	if v := value("max_visits"); v != "" {
+		if req.MaxVisits, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return req, fmt.Errorf("max_visits: %w", err)
+		}
+	}
+
+	for name, field := range map[string]**time.Time{
+		"expires_at":    &req.ExpiresAt,
+		"activate_at":   &req.ActivateAt,
+		"deactivate_at": &req.DeactivateAt,
+	} {
+		v := value(name)
+		if v == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return req, fmt.Errorf("%s: %w", name, err)
+		}
+		*field = &t
+	}
+
+	return req, nil
+}
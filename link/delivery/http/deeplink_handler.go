@@ -0,0 +1,80 @@
+package http
+
+import (
+	"bytes"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"html/template"
+	"net/http"
+)
+
+var deepLinkTemplate = template.Must(template.New("deeplink").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<meta name="robots" content="noindex">
+	<title>Opening the app</title>
+</head>
+<body>
+	<p>Opening the app&hellip; <a href="{{.Fallback}}">Continue</a> if nothing happens.</p>
+	<script>
+		(function () {
+			var fallback = {{.Fallback}};
+			var timer = setTimeout(function () { window.location.replace(fallback); }, 1500);
+			document.addEventListener("visibilitychange", function () {
+				if (document.hidden) { clearTimeout(timer); }
+			});
+			window.location.href = {{.AppUrl}};
+		})();
+	</script>
+</body>
+</html>
+`))
+
+type deepLinkPage struct {
+	AppUrl   string
+	Fallback string
+}
+
+func renderDeepLinkPage(page deepLinkPage) string {
+	var buf bytes.Buffer
+	if err := deepLinkTemplate.Execute(&buf, page); err != nil {
+		logrus.Error(err)
+	}
+
+	return buf.String()
+}
+
+// DeepLinkHandler serves the association files which let iOS and Android open short links in the apps
+type DeepLinkHandler struct {
+	AppleAppSiteAssociation []byte
+	AssetLinks              []byte
+}
+
+func NewDeepLinkHandler(e *echo.Echo, appleAppSiteAssociation []byte, assetLinks []byte) {
+	handler := &DeepLinkHandler{
+		AppleAppSiteAssociation: appleAppSiteAssociation,
+		AssetLinks:              assetLinks,
+	}
+
+	e.GET("/.well-known/apple-app-site-association", handler.GetAppleAppSiteAssociation)
+	e.GET("/apple-app-site-association", handler.GetAppleAppSiteAssociation)
+	e.GET("/.well-known/assetlinks.json", handler.GetAssetLinks)
+}
+
+func (dh *DeepLinkHandler) GetAppleAppSiteAssociation(c echo.Context) error {
+	if len(dh.AppleAppSiteAssociation) == 0 {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, dh.AppleAppSiteAssociation)
+}
+
+func (dh *DeepLinkHandler) GetAssetLinks(c echo.Context) error {
+	if len(dh.AssetLinks) == 0 {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, dh.AssetLinks)
+}
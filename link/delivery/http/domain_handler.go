@@ -0,0 +1,150 @@
+package http
+
+import (
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/labstack/echo"
+	"net/http"
+	"strconv"
+)
+
+type ResponseDomainObject struct {
+	Message string        `json:"message"`
+	Data    domain.Domain `json:"data"`
+}
+
+type ResponseDomainArray struct {
+	Message string          `json:"message"`
+	Data    []domain.Domain `json:"data"`
+}
+
+type DomainHandler struct {
+	DomainsUseCase domain.DomainsUseCase
+}
+
+func NewDomainHandler(e *echo.Echo, domainsUcase domain.DomainsUseCase) {
+	handler := &DomainHandler{
+		DomainsUseCase: domainsUcase,
+	}
+
+	e.GET("/domains", handler.FetchDomains)
+	e.POST("/domains", handler.StoreDomain)
+	e.GET("/domains/:id", handler.GetByID)
+	e.PUT("/domains/:id", handler.UpdateDomain)
+	e.DELETE("/domains/:id", handler.DeleteDomain)
+}
+
+func (dh *DomainHandler) FetchDomains(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	ctx := c.Request().Context()
+
+	list, err := dh.DomainsUseCase.Fetch(ctx, int64(limit))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseDomainArray{Message: "ok", Data: list})
+}
+
+func (dh *DomainHandler) GetByID(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	d, err := dh.DomainsUseCase.GetById(ctx, int64(id))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseDomainObject{Message: "ok", Data: d})
+}
+
+func (dh *DomainHandler) StoreDomain(c echo.Context) error {
+	var req domain.DomainRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	var ok bool
+	if ok, err = isRequestValid(&req); !ok {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	id, err := dh.DomainsUseCase.Store(ctx, domain.Domain{
+		Host:            req.Host,
+		DefaultRedirect: req.DefaultRedirect,
+		NotFoundPage:    req.NotFoundPage,
+		AliasLength:     req.AliasLength,
+		RedirectStatus:  req.RedirectStatus,
+	})
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	d, err := dh.DomainsUseCase.GetById(ctx, id)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, ResponseDomainObject{Message: "ok", Data: d})
+}
+
+func (dh *DomainHandler) UpdateDomain(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	var req domain.DomainRequest
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	var ok bool
+	if ok, err = isRequestValid(&req); !ok {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	_, err = dh.DomainsUseCase.Update(ctx, domain.Domain{
+		ID:              int64(id),
+		Host:            req.Host,
+		DefaultRedirect: req.DefaultRedirect,
+		NotFoundPage:    req.NotFoundPage,
+		AliasLength:     req.AliasLength,
+		RedirectStatus:  req.RedirectStatus,
+	})
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	d, err := dh.DomainsUseCase.GetById(ctx, int64(id))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseDomainObject{Message: "ok", Data: d})
+}
+
+func (dh *DomainHandler) DeleteDomain(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	err = dh.DomainsUseCase.Delete(ctx, int64(id))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
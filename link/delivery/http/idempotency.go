@@ -0,0 +1,111 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// defaultMaxIdempotentBody is the largest body read for the fingerprint when "idempotency.max_body" is not set
+	defaultMaxIdempotentBody = 10 << 20
+)
+
+// responseRecorder copies the body written to the client, so it can be stored with the idempotency key
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotency makes the requests with the Idempotency-Key header safe to retry. The response of the first
+// request is stored and replayed for the same key, the key used with another request is rejected.
+// Requests without the header are passed through.
+func Idempotency(uc domain.IdempotencyUseCase) echo.MiddlewareFunc {
+	maxBody := viper.GetInt64("idempotency.max_body")
+	if maxBody <= 0 {
+		maxBody = defaultMaxIdempotentBody
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				return c.JSON(http.StatusBadRequest, ResponseError{Message: "Idempotency-Key is too long"})
+			}
+
+			body, err := ioutil.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxBody))
+			if err != nil {
+				if _, ok := err.(*http.MaxBytesError); ok {
+					return c.JSON(http.StatusRequestEntityTooLarge, ResponseError{Message: err.Error()})
+				}
+
+				return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+			}
+			c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			ctx := c.Request().Context()
+
+			reservation, replay, err := uc.Begin(ctx, key, fingerprint(c.Request(), body))
+			if err != nil {
+				return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+			}
+
+			if replay {
+				c.Response().Header().Set(idempotentReplayedHeader, "true")
+				return c.JSONBlob(reservation.StatusCode, reservation.Response)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			err = next(c)
+
+			// the key is settled even when the client went away, the use case adds its own timeout
+			ctx = context.WithoutCancel(ctx)
+
+			// failed requests are not stored, the client may retry them with the same key
+			status := c.Response().Status
+			if err != nil || status >= http.StatusInternalServerError {
+				if releaseErr := uc.Release(ctx, reservation); releaseErr != nil {
+					logrus.Error(releaseErr)
+				}
+
+				return err
+			}
+
+			if completeErr := uc.Complete(ctx, reservation, status, recorder.body.Bytes()); completeErr != nil {
+				logrus.Error(completeErr)
+			}
+
+			return nil
+		}
+	}
+}
+
+// fingerprint identifies the request the idempotency key was sent with
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -1,160 +1,1342 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/iambakhodir/short-link/domain"
-	"github.com/iambakhodir/short-link/domain/random"
+	"github.com/iambakhodir/short-link/domain/query"
+	"github.com/iambakhodir/short-link/domain/reserved"
+	"github.com/iambakhodir/short-link/domain/useragent"
+	"github.com/iambakhodir/short-link/domain/variant"
 	"github.com/labstack/echo"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// previewSuffix appended to an alias shows the link's preview page, e.g. /abc123+
+const previewSuffix = "+"
+
+const defaultAliasLength = 6
+
+// headerETag and headerIfMatch carry the version of the link for the optimistic concurrency of updates
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+)
+
+// defaultBulkLimit is the maximum number of links created by one bulk request, "link.bulk_limit" overrides it
+const defaultBulkLimit = 500
+
+// defaultImportMaxSize is the maximum size of the imported CSV file, "link.import_max_size" overrides it
+const defaultImportMaxSize = 10 << 20
+
 type ResponseError struct {
 	Message string `json:"message"`
 }
 
-type ResponseSuccessObject struct {
-	Message string              `json:"message"`
-	Data    domain.LinkResponse `json:"data"`
+type ResponseSuccessObject struct {
+	Message string              `json:"message"`
+	Data    domain.LinkResponse `json:"data"`
+}
+
+type ResponseSuccessArray struct {
+	Message string                `json:"message"`
+	Data    []domain.LinkResponse `json:"data"`
+}
+
+type ResponseBulkArray struct {
+	Message string                  `json:"message"`
+	Data    []domain.LinkBulkResult `json:"data"`
+}
+
+type ResponseImportObject struct {
+	Message string            `json:"message"`
+	Data    domain.LinkImport `json:"data"`
+}
+
+type ResponseStatArray struct {
+	Message string              `json:"message"`
+	Data    []domain.VisitsStat `json:"data"`
+}
+
+type ResponseVariantArray struct {
+	Message string               `json:"message"`
+	Data    []domain.LinkVariant `json:"data"`
+}
+
+type LinkHandler struct {
+	LUseCase         domain.LinkUseCase
+	TagsUseCase      domain.TagsUseCase
+	LinkTagUseCase   domain.LinkTagUseCase
+	VisitsUseCase    domain.VisitsUseCase
+	VisitsWriter     domain.VisitsWriter
+	ReferrersUseCase domain.ReferrersUseCase
+	RulesUseCase     domain.RedirectRulesUseCase
+	VariantsUseCase  domain.LinkVariantsUseCase
+	DomainsUseCase   domain.DomainsUseCase
+	LinkImporter     domain.LinkImporter
+
+	passwordAttempts *attemptLimiter
+	trustProxy       bool
+	variantsSecret   []byte
+	disabledPage     []byte
+	reservedAliases  *reserved.Registry
+}
+
+func NewLinkHandler(e *echo.Echo, us domain.LinkUseCase, tagsUcase domain.TagsUseCase, linkTagUcase domain.LinkTagUseCase,
+	visitsUcase domain.VisitsUseCase, visitsWriter domain.VisitsWriter, referrersUcase domain.ReferrersUseCase,
+	rulesUcase domain.RedirectRulesUseCase, variantsUcase domain.LinkVariantsUseCase, domainsUcase domain.DomainsUseCase,
+	reservedAliases *reserved.Registry, idempotencyUcase domain.IdempotencyUseCase, linkImporter domain.LinkImporter) {
+	handler := &LinkHandler{
+		LUseCase:         us,
+		TagsUseCase:      tagsUcase,
+		LinkTagUseCase:   linkTagUcase,
+		VisitsUseCase:    visitsUcase,
+		VisitsWriter:     visitsWriter,
+		ReferrersUseCase: referrersUcase,
+		RulesUseCase:     rulesUcase,
+		VariantsUseCase:  variantsUcase,
+		DomainsUseCase:   domainsUcase,
+		LinkImporter:     linkImporter,
+		passwordAttempts: newAttemptLimiter(viper.GetInt("password.max_attempts"),
+			time.Duration(viper.GetInt("password.attempts_window"))*time.Second),
+		trustProxy:      viper.GetBool("server.trust_proxy"),
+		variantsSecret:  []byte(viper.GetString("variants.secret")),
+		disabledPage:    readDisabledPage(viper.GetString("link.disabled_page")),
+		reservedAliases: reservedAliases,
+	}
+
+	e.GET("/links", handler.FetchLinks)
+	e.GET("/links/:id", handler.GetByID)
+	e.PATCH("/links/:id", handler.UpdateLink)
+	e.PUT("/links/:id", handler.ReplaceLink)
+	e.POST("/links", handler.StoreLink, Idempotency(idempotencyUcase))
+	e.POST("/links/bulk", handler.StoreLinks, Idempotency(idempotencyUcase))
+	e.GET("/links/export", handler.ExportLinks)
+	e.POST("/links/import", handler.ImportLinks)
+	e.GET("/imports/:id", handler.GetImport)
+	e.DELETE("/links/:id", handler.DeleteLink)
+	e.POST("/links/:id/restore", handler.RestoreLink)
+	e.POST("/links/:id/disable", handler.DisableLink)
+	e.POST("/links/:id/enable", handler.EnableLink)
+	e.GET("/trash", handler.FetchTrash)
+	e.GET("/links/:id/browsers", handler.FetchBrowsersStat)
+	e.GET("/links/:id/devices", handler.FetchDevicesStat)
+	e.GET("/links/:id/referrers", handler.FetchTopReferrers)
+	e.GET("/links/:id/variants", handler.FetchVariants)
+	e.GET("/", handler.RedirectRoot)
+	e.GET("/:alias", handler.RedirectByAlias)
+	e.POST("/:alias", handler.UnlockByAlias)
+	e.GET("/:alias/preview", handler.PreviewByAlias)
+	e.GET("/:alias/go", handler.ContinueByAlias)
+}
+
+// ReserveRoutes reserves the first segment of every route registered so far, so aliases can't shadow them.
+// It must be called after all handlers are registered.
+func ReserveRoutes(e *echo.Echo, registry *reserved.Registry) {
+	for _, route := range e.Routes() {
+		registry.ReserveRoutes(route.Path)
+	}
+}
+
+func (lh *LinkHandler) RedirectByAlias(c echo.Context) error {
+	aliasParam := c.Param("alias")
+
+	if strings.HasSuffix(aliasParam, previewSuffix) {
+		return lh.preview(c, strings.TrimSuffix(aliasParam, previewSuffix))
+	}
+
+	link, d, err := lh.linkByAlias(c, aliasParam)
+	if err != nil {
+		return lh.lookupFailed(c, d, err)
+	}
+
+	if link.ForcePreview {
+		return lh.renderPreview(c, link)
+	}
+
+	return lh.open(c, link, d)
+}
+
+// RedirectRoot sends the visitor of the domain's root to its default redirect
+func (lh *LinkHandler) RedirectRoot(c echo.Context) error {
+	d, err := lh.DomainsUseCase.Resolve(c.Request().Context(), c.Request().Host)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	if d.DefaultRedirect != "" {
+		return c.Redirect(http.StatusFound, d.DefaultRedirect)
+	}
+
+	return c.JSON(http.StatusNotFound, ResponseError{Message: domain.ErrNotFound.Error()})
+}
+
+// linkByAlias looks the alias up in the namespace of the domain the request's Host resolves to
+func (lh *LinkHandler) linkByAlias(c echo.Context, alias string) (domain.Link, domain.Domain, error) {
+	ctx := c.Request().Context()
+
+	d, err := lh.DomainsUseCase.Resolve(ctx, c.Request().Host)
+	if err != nil {
+		return domain.Link{}, d, err
+	}
+
+	link, err := lh.LUseCase.GetByAlias(ctx, d.ID, alias)
+
+	return link, d, err
+}
+
+// lookupFailed answers an unknown alias with the domain's 404 page or default redirect
+func (lh *LinkHandler) lookupFailed(c echo.Context, d domain.Domain, err error) error {
+	if err == domain.ErrNotFound {
+		if d.NotFoundPage != "" {
+			return c.HTML(http.StatusNotFound, d.NotFoundPage)
+		}
+
+		if d.DefaultRedirect != "" {
+			return c.Redirect(http.StatusFound, d.DefaultRedirect)
+		}
+	}
+
+	return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+}
+
+// PreviewByAlias shows the link's destination instead of redirecting
+func (lh *LinkHandler) PreviewByAlias(c echo.Context) error {
+	return lh.preview(c, c.Param("alias"))
+}
+
+// ContinueByAlias follows the link from its preview page
+func (lh *LinkHandler) ContinueByAlias(c echo.Context) error {
+	link, d, err := lh.linkByAlias(c, c.Param("alias"))
+	if err != nil {
+		return lh.lookupFailed(c, d, err)
+	}
+
+	return lh.open(c, link, d)
+}
+
+// open asks for the password of a protected link, other links are followed right away
+func (lh *LinkHandler) open(c echo.Context, link domain.Link, d domain.Domain) error {
+	if link.Status == domain.LinkStatusDisabled {
+		return lh.disabled(c)
+	}
+
+	if link.PasswordHash.Valid {
+		switch link.State(time.Now()) {
+		case domain.LinkStateScheduled:
+			return lh.notActive(c)
+		case domain.LinkStateEnded:
+			return lh.expired(c)
+		}
+
+		password := c.Request().Header.Get(HeaderLinkPassword)
+		if password == "" {
+			return c.HTML(http.StatusOK, renderPasswordPage(passwordPage{Alias: link.Alias}))
+		}
+
+		err := lh.checkPassword(c, link, password)
+		if err != nil {
+			return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		}
+	}
+
+	return lh.follow(c, link, redirectStatus(link, d))
+}
+
+func (lh *LinkHandler) preview(c echo.Context, alias string) error {
+	link, d, err := lh.linkByAlias(c, alias)
+	if err != nil {
+		return lh.lookupFailed(c, d, err)
+	}
+
+	return lh.renderPreview(c, link)
+}
+
+func (lh *LinkHandler) renderPreview(c echo.Context, link domain.Link) error {
+	if link.Status == domain.LinkStatusDisabled {
+		return lh.disabled(c)
+	}
+
+	ctx := c.Request().Context()
+
+	tags, err := lh.TagsUseCase.FetchByLinkId(ctx, link.ID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	continueUrl := "/" + url.PathEscape(link.Alias) + "/go"
+	if rawQuery := c.Request().URL.RawQuery; rawQuery != "" {
+		continueUrl += "?" + rawQuery
+	}
+
+	page := previewPage{
+		Description: link.Description.String,
+		Tags:        tags,
+		CreatedAt:   link.CreatedAt,
+		Protected:   link.PasswordHash.Valid,
+		ContinueUrl: continueUrl,
+	}
+	if !page.Protected {
+		page.Target = link.Target
+	}
+
+	c.Response().Header().Set("X-Robots-Tag", "noindex")
+
+	return c.HTML(http.StatusOK, renderPreviewPage(page))
+}
+
+// UnlockByAlias checks the password submitted from the protected link's form
+func (lh *LinkHandler) UnlockByAlias(c echo.Context) error {
+	link, d, err := lh.linkByAlias(c, c.Param("alias"))
+	if err != nil {
+		return lh.lookupFailed(c, d, err)
+	}
+
+	err = lh.checkPassword(c, link, c.FormValue("password"))
+	if err != nil {
+		return c.HTML(getStatusCode(err), renderPasswordPage(passwordPage{Alias: link.Alias, Error: err.Error()}))
+	}
+
+	// the form is posted, so the visitor must follow the redirect with GET
+	return lh.follow(c, link, http.StatusSeeOther)
+}
+
+// follow counts the visit of the link and redirects to its target
+func (lh *LinkHandler) follow(c echo.Context, link domain.Link, status int) error {
+	ctx := c.Request().Context()
+
+	err := lh.LUseCase.Hit(ctx, link)
+	if err == domain.ErrLinkExpired {
+		return lh.expired(c)
+	}
+	if err == domain.ErrLinkNotActive {
+		return lh.notActive(c)
+	}
+	if err == domain.ErrLinkDisabled {
+		return lh.disabled(c)
+	}
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	target, variantId := lh.target(c, link)
+
+	target, err = passQuery(c, link, target)
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	visit := newVisit(c, link.ID, lh.trustProxy)
+	visit.VariantId = variantId
+
+	err = lh.VisitsWriter.Write(visit)
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	// the urls are checked again as the page runs them as scripts, links stored before the
+	// schemes were validated may still hold unsafe ones
+	appUrl, storeUrl := link.AppLink(useragent.Parse(c.Request().UserAgent()).OS)
+	if domain.IsAppUrl(appUrl) {
+		fallback := target
+		if domain.IsWebUrl(storeUrl) {
+			fallback = storeUrl
+		}
+		if !domain.IsWebUrl(fallback) {
+			return c.Redirect(status, target)
+		}
+
+		return c.HTML(http.StatusOK, renderDeepLinkPage(deepLinkPage{AppUrl: appUrl, Fallback: fallback}))
+	}
+
+	return c.Redirect(status, target)
+}
+
+// target returns the target of the first redirect rule matching the request, then the visitor's
+// A/B variant and at last the link's target. The id of the chosen variant is returned as well.
+func (lh *LinkHandler) target(c echo.Context, link domain.Link) (string, int64) {
+	req := c.Request()
+	ctx := req.Context()
+
+	rule, ok, err := lh.RulesUseCase.Match(ctx, link.ID, domain.RuleVisitor{
+		UserAgent:      req.UserAgent(),
+		AcceptLanguage: req.Header.Get("Accept-Language"),
+		IP:             net.ParseIP(clientIP(c, lh.trustProxy)),
+		Query:          c.QueryParams(),
+		Time:           time.Now(),
+	})
+	if err != nil {
+		logrus.Error(err)
+	}
+	if ok {
+		return rule.Target, 0
+	}
+
+	variants, err := lh.VariantsUseCase.FetchByLinkId(ctx, link.ID)
+	if err != nil {
+		logrus.Error(err)
+		return link.Target, 0
+	}
+
+	if v, ok := lh.pickVariant(c, link, variants); ok {
+		return v.Target, v.ID
+	}
+
+	return link.Target, 0
+}
+
+// pickVariant keeps the visitor on the variant from the signed cookie, new visitors
+// are assigned by the hash of their IP and User-Agent
+func (lh *LinkHandler) pickVariant(c echo.Context, link domain.Link, variants []domain.LinkVariant) (domain.LinkVariant, bool) {
+	if len(variants) == 0 {
+		return domain.LinkVariant{}, false
+	}
+
+	cookieName := "sl_variant_" + strconv.FormatInt(link.ID, 10)
+
+	if len(lh.variantsSecret) > 0 {
+		if cookie, err := c.Cookie(cookieName); err == nil {
+			if value, ok := variant.Verify(lh.variantsSecret, cookie.Value); ok {
+				for _, v := range variants {
+					if strconv.FormatInt(v.ID, 10) == value {
+						return v, true
+					}
+				}
+			}
+		}
+	}
+
+	v, ok := lh.VariantsUseCase.Pick(variants, clientIP(c, lh.trustProxy)+"|"+c.Request().UserAgent())
+	if !ok {
+		return domain.LinkVariant{}, false
+	}
+
+	if len(lh.variantsSecret) > 0 {
+		c.SetCookie(&http.Cookie{
+			Name:     cookieName,
+			Value:    variant.Sign(lh.variantsSecret, strconv.FormatInt(v.ID, 10)),
+			Path:     "/" + link.Alias,
+			MaxAge:   int((30 * 24 * time.Hour).Seconds()),
+			HttpOnly: true,
+		})
+	}
+
+	return v, true
+}
+
+// expired sends the visitor to "link.expired_url" or answers 410 Gone
+func (lh *LinkHandler) expired(c echo.Context) error {
+	if fallback := viper.GetString("link.expired_url"); fallback != "" {
+		return c.Redirect(http.StatusFound, fallback)
+	}
+
+	return c.JSON(http.StatusGone, ResponseError{Message: domain.ErrLinkExpired.Error()})
+}
+
+// notActive sends the visitor of a scheduled link to "link.scheduled_url"
+// or answers with "link.scheduled_status", 404 Not Found by default
+func (lh *LinkHandler) notActive(c echo.Context) error {
+	if fallback := viper.GetString("link.scheduled_url"); fallback != "" {
+		return c.Redirect(http.StatusFound, fallback)
+	}
+
+	status := viper.GetInt("link.scheduled_status")
+	if status == 0 {
+		status = http.StatusNotFound
+	}
+
+	return c.JSON(status, ResponseError{Message: domain.ErrLinkNotActive.Error()})
+}
+
+// disabled answers the visitor of a disabled link with "link.disabled_status", 404 Not Found or
+// 451 Unavailable For Legal Reasons, showing "link.disabled_page" when it is configured
+func (lh *LinkHandler) disabled(c echo.Context) error {
+	status := http.StatusNotFound
+	if viper.GetInt("link.disabled_status") == http.StatusUnavailableForLegalReasons {
+		status = http.StatusUnavailableForLegalReasons
+	}
+
+	if len(lh.disabledPage) > 0 {
+		return c.HTMLBlob(status, lh.disabledPage)
+	}
+
+	return c.JSON(status, ResponseError{Message: domain.ErrLinkDisabled.Error()})
+}
+
+// readDisabledPage returns content of the optional disabled page, a missing page falls back to the JSON answer
+func readDisabledPage(path string) []byte {
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+
+	return content
+}
+
+func (lh *LinkHandler) checkPassword(c echo.Context, link domain.Link, password string) error {
+	key := clientIP(c, lh.trustProxy) + "|" + strconv.FormatInt(link.ID, 10)
+	if !lh.passwordAttempts.Allow(key) {
+		return domain.ErrTooManyAttempts
+	}
+
+	err := lh.LUseCase.CheckPassword(link, password)
+	if err != nil {
+		lh.passwordAttempts.Fail(key)
+		return err
+	}
+
+	lh.passwordAttempts.Reset(key)
+
+	return nil
+}
+
+func renderPasswordPage(page passwordPage) string {
+	var buf bytes.Buffer
+	if err := passwordTemplate.Execute(&buf, page); err != nil {
+		logrus.Error(err)
+	}
+
+	return buf.String()
+}
+
+func (lh *LinkHandler) GetByID(c echo.Context) error {
+	idParam, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	link, err := lh.LUseCase.GetById(ctx, int64(idParam))
+
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	tags, err := lh.TagsUseCase.FetchByLinkId(ctx, link.ID)
+
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	link.Variants, err = lh.VariantsUseCase.FetchWithVisitsByLinkId(ctx, link.ID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	c.Response().Header().Set(headerETag, linkETag(link))
+
+	return c.JSON(http.StatusOK, ResponseSuccessObject{Message: "ok", Data: toLinkResponse(link, tags)})
+}
+
+// UpdateLink changes the fields of the link present in the request, If-Match must match the link's ETag when it is sent
+func (lh *LinkHandler) UpdateLink(c echo.Context) error {
+	var req domain.LinkPatchRequest
+
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	var ok bool
+	if ok, err = isRequestValid(&req); !ok {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	current, currentTags, err := lh.linkToUpdate(c)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	link := current
+	if req.Target != nil {
+		link.Target = *req.Target
+	}
+	if req.Alias != nil {
+		link.Alias = *req.Alias
+	}
+	if req.Description != nil {
+		link.Description = nullString(*req.Description)
+	}
+
+	tags := req.Tags
+	if tags == nil {
+		tags = make([]string, 0, len(currentTags))
+		for _, t := range currentTags {
+			tags = append(tags, t.Name)
+		}
+	}
+
+	removed := make(map[string]bool, len(req.RemoveTags))
+	for _, name := range req.RemoveTags {
+		removed[name] = true
+	}
+
+	names := make([]string, 0, len(tags)+len(req.AddTags))
+	for _, name := range append(tags, req.AddTags...) {
+		if !removed[name] {
+			names = append(names, name)
+		}
+	}
+
+	return lh.saveLink(c, current, link, names, false)
+}
+
+// ReplaceLink replaces the link with the request, which is validated like StoreLink. An empty alias
+// or password keeps the current one, the status and the visits of the link are kept.
+func (lh *LinkHandler) ReplaceLink(c echo.Context) error {
+	var req domain.LinkRequest
+
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	var ok bool
+	if ok, err = isRequestValid(&req); !ok {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	current, _, err := lh.linkToUpdate(c)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	d, err := lh.requestDomain(ctx, req.Domain)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	link := newLink(req, d)
+	link.ID = current.ID
+	link.UserId = current.UserId
+	link.Status = current.Status
+	link.VisitsCount = current.VisitsCount
+	link.CreatedAt = current.CreatedAt
+	link.UpdatedAt = current.UpdatedAt
+	if link.Alias == "" {
+		link.Alias = current.Alias
+	}
+	if link.Password == "" {
+		link.PasswordHash = current.PasswordHash
+	}
+
+	// unchanged variants are kept with their ids and visits
+	currentVariants, err := lh.VariantsUseCase.FetchByLinkId(ctx, current.ID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return lh.saveLink(c, current, link, req.Tags, !sameVariants(currentVariants, link.Variants))
+}
+
+// sameVariants reports whether the variants have the same targets and weights in the same order
+func sameVariants(a []domain.LinkVariant, b []domain.LinkVariant) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Target != b[i].Target || a[i].Weight != b[i].Weight {
+			return false
+		}
+	}
+
+	return true
+}
+
+// linkToUpdate loads the link of the request with its tags and checks it against If-Match
+func (lh *LinkHandler) linkToUpdate(c echo.Context) (domain.Link, []domain.Tags, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return domain.Link{}, nil, domain.ErrNotFound
+	}
+
+	ctx := c.Request().Context()
+
+	link, err := lh.LUseCase.GetById(ctx, int64(id))
+	if err != nil {
+		return domain.Link{}, nil, err
+	}
+
+	if ifMatch := c.Request().Header.Get(headerIfMatch); ifMatch != "" && !matchETag(ifMatch, linkETag(link)) {
+		return domain.Link{}, nil, domain.ErrPreconditionFailed
+	}
+
+	tags, err := lh.TagsUseCase.FetchByLinkId(ctx, link.ID)
+	if err != nil {
+		return domain.Link{}, nil, err
+	}
+
+	return link, tags, nil
+}
+
+// saveLink stores the changed link with the tags of names unless it was modified since it was read.
+// The variants of the link replace the stored ones with replaceVariants.
+func (lh *LinkHandler) saveLink(c echo.Context, current domain.Link, link domain.Link, names []string,
+	replaceVariants bool) error {
+	if (link.Alias != current.Alias || link.DomainId != current.DomainId) && lh.reservedAliases.IsReserved(link.Alias) {
+		return c.JSON(getStatusCode(domain.ErrAliasReserved), ResponseError{Message: domain.ErrAliasReserved.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	tagIds, err := lh.tagIds(ctx, names)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	update := domain.LinkUpdate{Link: link, TagIds: tagIds, ReplaceVariants: replaceVariants}
+	stored, err := lh.LUseCase.UpdateIfUnmodified(ctx, update, current.UpdatedAt)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	res, err := lh.linkResponse(ctx, link.ID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	c.Response().Header().Set(headerETag, linkETag(stored))
+
+	return c.JSON(http.StatusOK, ResponseSuccessObject{Message: "ok", Data: res})
+}
+
+// linkETag identifies the version of the link, it changes with every update of the link's updated_at
+func linkETag(link domain.Link) string {
+	return `"` + strconv.FormatInt(link.ID, 36) + "-" + strconv.FormatInt(link.UpdatedAt.Unix(), 36) + `"`
+}
+
+// matchETag reports whether the If-Match header lists the ETag, weak ETags are compared by their value
+func matchETag(ifMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (lh *LinkHandler) FetchLinks(c echo.Context) error {
+	limitParam := c.QueryParam("limit")
+	limit, _ := strconv.Atoi(limitParam)
+	//cursor := c.QueryParam("cursor")
+	filter, err := linkFilter(c)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+	filter.Limit = int64(limit)
+
+	ctx := c.Request().Context()
+
+	listLinks, err := lh.LUseCase.Fetch(ctx, filter)
+
+	data := make([]domain.LinkResponse, 0)
+
+	for _, l := range listLinks {
+		tags, err := lh.TagsUseCase.FetchByLinkId(ctx, l.ID)
+		if err != nil {
+			return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		}
+		data = append(data, toLinkResponse(l, tags))
+	}
+
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, ResponseSuccessArray{Message: "ok", Data: data})
+}
+
+// linkFilter reads the state and status of the link listing from the query
+func linkFilter(c echo.Context) (domain.LinkFilter, error) {
+	state := c.QueryParam("state")
+	switch state {
+	case "", domain.LinkStateScheduled, domain.LinkStateLive, domain.LinkStateEnded:
+	default:
+		return domain.LinkFilter{}, domain.ErrBadParamInput
+	}
+
+	status := c.QueryParam("status")
+	switch status {
+	case "", domain.LinkStatusActive, domain.LinkStatusDisabled:
+	default:
+		return domain.LinkFilter{}, domain.ErrBadParamInput
+	}
+
+	return domain.LinkFilter{State: state, Status: status}, nil
+}
+
+// ExportLinks streams the filtered links as CSV, page by page
+func (lh *LinkHandler) ExportLinks(c echo.Context) error {
+	if format := c.QueryParam("format"); format != "" && format != "csv" {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: domain.ErrBadParamInput.Error()})
+	}
+
+	filter, err := linkFilter(c)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="links.csv"`)
+	res.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(res)
+	if err = w.Write(linkCSVColumns); err != nil {
+		return err
+	}
+
+	hosts := map[int64]string{0: ""}
+	err = lh.LUseCase.Export(ctx, filter, func(links []domain.Link) error {
+		ids := make([]int64, 0, len(links))
+		for _, l := range links {
+			ids = append(ids, l.ID)
+		}
+
+		tags, err := lh.TagsUseCase.FetchByLinkIds(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for _, l := range links {
+			host, ok := hosts[l.DomainId]
+			if !ok {
+				d, err := lh.DomainsUseCase.GetById(ctx, l.DomainId)
+				if err != nil && err != domain.ErrNotFound {
+					return err
+				}
+				host = d.Host
+				hosts[l.DomainId] = host
+			}
+
+			if err = w.Write(linkCSVRecord(l, tags[l.ID], host)); err != nil {
+				return err
+			}
+		}
+
+		w.Flush()
+		res.Flush()
+
+		return w.Error()
+	})
+	if err != nil {
+		// the status is already sent, the client sees a truncated file
+		logrus.Error(err)
+	}
+
+	return nil
+}
+
+// ImportLinks validates the rows of the uploaded CSV file and queues their import, ?dry_run=true only validates them.
+// The row errors are reported by the import, see GetImport.
+func (lh *LinkHandler) ImportLinks(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	maxSize := viper.GetInt64("link.import_max_size")
+	if maxSize <= 0 {
+		maxSize = defaultImportMaxSize
+	}
+
+	if file.Size > maxSize {
+		return c.JSON(http.StatusRequestEntityTooLarge,
+			ResponseError{Message: fmt.Sprintf("CSV file must not be larger than %d bytes", maxSize)})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	defer src.Close()
+
+	csvRows, err := readLinkCSV(src)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run"))
+
+	ctx := c.Request().Context()
+
+	domains := make(map[string]domain.Domain)
+	rows := make([]domain.LinkImportRow, 0, len(csvRows))
+	for _, r := range csvRows {
+		row := domain.LinkImportRow{Row: r.line, Tags: r.req.Tags, Err: r.err}
+		if row.Err == nil {
+			row.Item, row.Err = lh.linkItem(ctx, r.req, domains)
+		}
+
+		rows = append(rows, row)
+	}
+
+	job, err := lh.LinkImporter.Start(ctx, rows, dryRun)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusAccepted, ResponseImportObject{Message: "ok", Data: job})
+}
+
+// GetImport returns the progress and the row errors of the import
+func (lh *LinkHandler) GetImport(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	job, err := lh.LinkImporter.GetById(c.Request().Context(), int64(id))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseImportObject{Message: "ok", Data: job})
+}
+
+func (lh *LinkHandler) StoreLink(c echo.Context) error {
+	var req domain.LinkRequest
+
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	var ok bool
+	if ok, err = isRequestValid(&req); !ok {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	d, err := lh.requestDomain(ctx, req.Domain)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	if req.Alias != "" && lh.reservedAliases.IsReserved(req.Alias) {
+		return c.JSON(getStatusCode(domain.ErrAliasReserved), ResponseError{Message: domain.ErrAliasReserved.Error()})
+	}
+
+	link := newLink(req, d)
+
+	if link.Alias == "" && dedupe(req) {
+		// link.UserId is 0 until requests are authenticated, see dedupe
+		existing, err := lh.LUseCase.GetByTarget(ctx, link.UserId, link.DomainId, link.Target)
+		if err != nil && err != domain.ErrNotFound {
+			return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		}
+
+		if err == nil {
+			res, err := lh.linkResponse(ctx, existing.ID)
+			if err != nil {
+				return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+			}
+
+			// a request with its own settings gets a new link
+			if lh.sameSettings(link, req.Tags, existing, res) {
+				return c.JSON(http.StatusOK, ResponseSuccessObject{Message: "ok", Data: res})
+			}
+		}
+	}
+
+	tagIds, err := lh.tagIds(ctx, req.Tags)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	// the link is stored as a batch of one, so it is written with its variants and tags in one transaction
+	item := domain.LinkBatchItem{Link: link, Length: aliasLength(req.Length, d), TagIds: tagIds}
+	results, err := lh.LUseCase.StoreBatch(ctx, []domain.LinkBatchItem{item}, true)
+	if err == nil {
+		err = results[0].Err
+	}
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	res, err := lh.linkResponse(ctx, results[0].Link.ID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, ResponseSuccessObject{Message: "ok", Data: res})
+
+}
+
+// StoreLinks creates the links of the request body array, with ?atomic=true either all of them or none.
+// Every link is reported at its position in the request.
+func (lh *LinkHandler) StoreLinks(c echo.Context) error {
+	var reqs []domain.LinkRequest
+
+	err := c.Bind(&reqs)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	limit := viper.GetInt("link.bulk_limit")
+	if limit <= 0 {
+		limit = defaultBulkLimit
+	}
+
+	if len(reqs) == 0 || len(reqs) > limit {
+		return c.JSON(http.StatusUnprocessableEntity,
+			ResponseError{Message: fmt.Sprintf("Request must have from 1 to %d links", limit)})
+	}
+
+	atomic, _ := strconv.ParseBool(c.QueryParam("atomic"))
+
+	ctx := c.Request().Context()
+
+	domains := make(map[string]domain.Domain)
+	tagIds := make(map[string]int64)
+	failed := make([]error, len(reqs))
+	items := make([]domain.LinkBatchItem, 0, len(reqs))
+	positions := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		item, err := lh.batchItem(ctx, req, domains, tagIds)
+		if err != nil {
+			failed[i] = err
+			continue
+		}
+
+		items = append(items, item)
+		positions = append(positions, i)
+	}
+
+	stored := make([]domain.LinkBatchResult, 0)
+	if len(items) > 0 && (!atomic || len(items) == len(reqs)) {
+		stored, err = lh.LUseCase.StoreBatch(ctx, items, atomic)
+		if err != nil {
+			return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		}
+	}
+
+	results := make([]domain.LinkBulkResult, len(reqs))
+	for i := range results {
+		results[i].Index = i
+		if failed[i] != nil {
+			results[i].Error = failed[i].Error()
+		} else if atomic {
+			results[i].Error = domain.ErrBatchAborted.Error()
+		}
+	}
+
+	created := 0
+	for n, res := range stored {
+		i := positions[n]
+		if res.Err != nil {
+			results[i].Error = res.Err.Error()
+			continue
+		}
+
+		tags := make([]domain.Tags, 0, len(reqs[i].Tags))
+		for _, name := range reqs[i].Tags {
+			tags = append(tags, domain.Tags{ID: tagIds[name], Name: name})
+		}
+
+		linkRes := toLinkResponse(res.Link, tags)
+		results[i].Link = &linkRes
+		results[i].Error = ""
+		created++
+	}
+
+	status := http.StatusMultiStatus
+	switch created {
+	case len(reqs):
+		status = http.StatusCreated
+	case 0:
+		status = http.StatusUnprocessableEntity
+	}
+
+	return c.JSON(status, ResponseBulkArray{Message: "ok", Data: results})
+}
+
+// batchItem validates the request of the bulk creation and turns it into the link with its tags,
+// domains and tags are looked up once per request
+func (lh *LinkHandler) batchItem(ctx context.Context, req domain.LinkRequest, domains map[string]domain.Domain,
+	tagIds map[string]int64) (domain.LinkBatchItem, error) {
+	item, err := lh.linkItem(ctx, req, domains)
+	if err != nil {
+		return domain.LinkBatchItem{}, err
+	}
+
+	for _, name := range req.Tags {
+		id, ok := tagIds[name]
+		if !ok {
+			var err error
+			id, err = lh.TagsUseCase.FirstOrCreate(ctx, domain.Tags{Name: name})
+			if err != nil {
+				return domain.LinkBatchItem{}, err
+			}
+			tagIds[name] = id
+		}
+
+		item.TagIds = append(item.TagIds, id)
+	}
+
+	return item, nil
 }
 
-type ResponseSuccessArray struct {
-	Message string                `json:"message"`
-	Data    []domain.LinkResponse `json:"data"`
+// linkItem validates the request and turns it into the link without its tags, domains are cached in domains
+func (lh *LinkHandler) linkItem(ctx context.Context, req domain.LinkRequest,
+	domains map[string]domain.Domain) (domain.LinkBatchItem, error) {
+	if ok, err := isRequestValid(&req); !ok {
+		return domain.LinkBatchItem{}, err
+	}
+
+	d, ok := domains[req.Domain]
+	if !ok {
+		var err error
+		d, err = lh.requestDomain(ctx, req.Domain)
+		if err != nil {
+			return domain.LinkBatchItem{}, err
+		}
+		domains[req.Domain] = d
+	}
+
+	if req.Alias != "" && lh.reservedAliases.IsReserved(req.Alias) {
+		return domain.LinkBatchItem{}, domain.ErrAliasReserved
+	}
+
+	return domain.LinkBatchItem{Link: newLink(req, d), Length: aliasLength(req.Length, d)}, nil
 }
 
-type LinkHandler struct {
-	LUseCase       domain.LinkUseCase
-	TagsUseCase    domain.TagsUseCase
-	LinkTagUseCase domain.LinkTagUseCase
+// requestDomain returns the domain of the requested host, empty host is the primary domain
+func (lh *LinkHandler) requestDomain(ctx context.Context, host string) (domain.Domain, error) {
+	if host == "" {
+		return domain.Domain{}, nil
+	}
+
+	d, err := lh.DomainsUseCase.GetByHost(ctx, host)
+	if err == domain.ErrNotFound {
+		return domain.Domain{}, domain.ErrBadParamInput
+	}
+
+	return d, err
 }
 
-func NewLinkHandler(e *echo.Echo, us domain.LinkUseCase, tagsUcase domain.TagsUseCase, linkTagUcase domain.LinkTagUseCase) {
-	handler := &LinkHandler{
-		LUseCase:       us,
-		TagsUseCase:    tagsUcase,
-		LinkTagUseCase: linkTagUcase,
+// newLink returns the link of the create request in the domain
+func newLink(req domain.LinkRequest, d domain.Domain) domain.Link {
+	link := domain.Link{
+		DomainId:        d.ID,
+		Target:          req.Target,
+		Alias:           req.Alias,
+		Description:     nullString(req.Description),
+		RedirectType:    req.RedirectType,
+		ExpiresAt:       nullTime(req.ExpiresAt),
+		ActivateAt:      nullTime(req.ActivateAt),
+		DeactivateAt:    nullTime(req.DeactivateAt),
+		MaxVisits:       sql.NullInt64{Int64: req.MaxVisits, Valid: req.MaxVisits > 0},
+		Password:        req.Password,
+		IosUrl:          nullString(req.IosUrl),
+		IosStoreUrl:     nullString(req.IosStoreUrl),
+		AndroidUrl:      nullString(req.AndroidUrl),
+		AndroidStoreUrl: nullString(req.AndroidStoreUrl),
+		QueryPolicy:     req.QueryPolicy,
+		QueryAllowlist:  nullString(strings.Join(req.QueryAllowlist, ",")),
+		UtmParams:       utmParams(req.Utm),
+		ForcePreview:    req.ForcePreview,
 	}
 
-	e.GET("/links", handler.FetchLinks)
-	e.GET("/links/:id", handler.GetByID)
-	e.POST("/links", handler.StoreLink)
-	e.DELETE("/links/:id", handler.DeleteLink)
-	e.GET("/:alias", handler.RedirectByAlias)
+	for _, v := range req.Variants {
+		link.Variants = append(link.Variants, domain.LinkVariant{Target: v.Target, Weight: v.Weight})
+	}
+
+	return link
 }
 
-func (lh *LinkHandler) RedirectByAlias(c echo.Context) error {
-	aliasParam := c.QueryParam("limit")
-	ctx := c.Request().Context()
+// linkResponse loads the link with its variants and tags
+func (lh *LinkHandler) linkResponse(ctx context.Context, id int64) (domain.LinkResponse, error) {
+	link, err := lh.LUseCase.GetById(ctx, id)
+	if err != nil {
+		return domain.LinkResponse{}, err
+	}
 
-	link, err := lh.LUseCase.GetByAlias(ctx, aliasParam)
+	link.Variants, err = lh.VariantsUseCase.FetchByLinkId(ctx, link.ID)
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return domain.LinkResponse{}, err
+	}
+
+	tags, err := lh.TagsUseCase.FetchByLinkId(ctx, link.ID)
+	if err != nil {
+		return domain.LinkResponse{}, err
 	}
 
-	return c.Redirect(http.StatusMovedPermanently, link.Target)
+	return toLinkResponse(link, tags), nil
 }
 
-func (lh *LinkHandler) GetByID(c echo.Context) error {
-	idParam, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+// dedupe reports whether the request asks for the existing link of its target, "link.dedupe" is the default.
+// The API has no users yet, every link has user id 0, so links are deduplicated per domain and the
+// server-wide setting stands in for a user default.
+func dedupe(req domain.LinkRequest) bool {
+	if req.Dedupe != nil {
+		return *req.Dedupe
 	}
 
-	ctx := c.Request().Context()
+	return viper.GetBool("link.dedupe")
+}
 
-	link, err := lh.LUseCase.GetById(ctx, int64(idParam))
+// sameSettings reports whether the new link would be the same as the existing one, res is the
+// response of the existing link with its variants and tags
+func (lh *LinkHandler) sameSettings(link domain.Link, tags []string, existing domain.Link, res domain.LinkResponse) bool {
+	if !link.SameSettings(existing) {
+		return false
+	}
+
+	if link.Password != "" {
+		if !existing.PasswordHash.Valid || lh.LUseCase.CheckPassword(existing, link.Password) != nil {
+			return false
+		}
+	} else if existing.PasswordHash.Valid {
+		return false
+	}
+
+	variants := make(map[domain.LinkVariant]int, len(link.Variants))
+	for _, v := range link.Variants {
+		variants[domain.LinkVariant{Target: v.Target, Weight: v.Weight}]++
+	}
+	for _, v := range res.Variants {
+		variants[domain.LinkVariant{Target: v.Target, Weight: v.Weight}]--
+	}
+	for _, n := range variants {
+		if n != 0 {
+			return false
+		}
+	}
+
+	names := make(map[string]bool, len(tags))
+	for _, name := range tags {
+		names[name] = true
+	}
+	if len(names) != len(res.Tags) {
+		return false
+	}
+	for _, t := range res.Tags {
+		if !names[t.Name] {
+			return false
+		}
+	}
 
+	return true
+}
+
+func (lh *LinkHandler) DeleteLink(c echo.Context) error {
+	idParam, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
 	}
 
-	tags, err := lh.TagsUseCase.FetchByLinkId(ctx, link.ID)
+	id := int64(idParam)
+	ctx := c.Request().Context()
 
+	err = lh.LUseCase.Delete(ctx, id)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, ResponseSuccessObject{Message: "ok", Data: domain.LinkResponse{
-		ID:          link.ID,
-		Alias:       link.Alias,
-		Target:      link.Target,
-		Description: "",
-		CreatedAt:   link.CreatedAt,
-		Tags:        tags,
-	}})
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (lh *LinkHandler) FetchLinks(c echo.Context) error {
-	limitParam := c.QueryParam("limit")
-	limit, _ := strconv.Atoi(limitParam)
-	//cursor := c.QueryParam("cursor")
+// FetchTrash lists the deleted links which are not purged yet
+func (lh *LinkHandler) FetchTrash(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
 	ctx := c.Request().Context()
 
-	listLinks, err := lh.LUseCase.Fetch(ctx, int64(limit))
+	listLinks, err := lh.LUseCase.Fetch(ctx, domain.LinkFilter{Limit: int64(limit), Deleted: true})
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
 
 	data := make([]domain.LinkResponse, 0)
-
 	for _, l := range listLinks {
 		tags, err := lh.TagsUseCase.FetchByLinkId(ctx, l.ID)
 		if err != nil {
 			return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 		}
-		data = append(data, domain.LinkResponse{
-			ID:          l.ID,
-			Target:      l.Target,
-			Alias:       l.Alias,
-			Description: "",
-			CreatedAt:   l.CreatedAt,
-			Tags:        tags,
-		})
+		data = append(data, toLinkResponse(l, tags))
 	}
 
-	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
-	}
 	return c.JSON(http.StatusOK, ResponseSuccessArray{Message: "ok", Data: data})
 }
 
-func (lh *LinkHandler) StoreLink(c echo.Context) error {
-	var req domain.LinkRequest
+// DisableLink pauses the link without deleting it
+func (lh *LinkHandler) DisableLink(c echo.Context) error {
+	return lh.changeStatus(c, lh.LUseCase.Disable)
+}
 
-	err := c.Bind(&req)
+// EnableLink resumes the disabled link
+func (lh *LinkHandler) EnableLink(c echo.Context) error {
+	return lh.changeStatus(c, lh.LUseCase.Enable)
+}
+
+func (lh *LinkHandler) changeStatus(c echo.Context, change func(ctx context.Context, id int64) error) error {
+	idParam, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
 	}
 
-	var ok bool
-	if ok, err = isRequestValid(&req); !ok {
-		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	id := int64(idParam)
+	ctx := c.Request().Context()
+
+	err = change(ctx, id)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	alias := req.Alias
-	length := req.Length
+	link, err := lh.LUseCase.GetById(ctx, id)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
 
-	if alias == "" {
-		if length > 0 {
-			alias = random.NewRandomString(length) //TODO improve generator
-		} else {
-			alias = random.NewRandomString(viper.GetInt("alias_length")) //TODO improve generator
-		}
+	tags, err := lh.TagsUseCase.FetchByLinkId(ctx, link.ID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseSuccessObject{Message: "ok", Data: toLinkResponse(link, tags)})
+}
+
+func (lh *LinkHandler) RestoreLink(c echo.Context) error {
+	idParam, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
 	}
 
+	id := int64(idParam)
 	ctx := c.Request().Context()
 
-	id, err := lh.LUseCase.Store(ctx, domain.Link{
-		Target: req.Target,
-		Alias:  alias,
-	})
+	err = lh.LUseCase.Restore(ctx, id)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	lh.createAndAttachTags(ctx, id, req.Tags)
-
 	link, err := lh.LUseCase.GetById(ctx, id)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
@@ -165,32 +1347,218 @@ func (lh *LinkHandler) StoreLink(c echo.Context) error {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	return c.JSON(http.StatusCreated, ResponseSuccessObject{Message: "ok", Data: domain.LinkResponse{
-		ID:          link.ID,
-		Alias:       link.Alias,
-		Target:      link.Target,
-		Description: "",
-		CreatedAt:   link.CreatedAt,
-		Tags:        tags,
-	}})
+	return c.JSON(http.StatusOK, ResponseSuccessObject{Message: "ok", Data: toLinkResponse(link, tags)})
+}
+
+func (lh *LinkHandler) FetchBrowsersStat(c echo.Context) error {
+	idParam, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	ctx := c.Request().Context()
+
+	stat, err := lh.VisitsUseCase.FetchBrowsersStat(ctx, int64(idParam), int64(limit))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
 
+	return c.JSON(http.StatusOK, ResponseStatArray{Message: "ok", Data: stat})
 }
 
-func (lh *LinkHandler) DeleteLink(c echo.Context) error {
+func (lh *LinkHandler) FetchDevicesStat(c echo.Context) error {
 	idParam, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
 	}
 
-	id := int64(idParam)
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
 	ctx := c.Request().Context()
 
-	err = lh.LUseCase.Delete(ctx, id)
+	stat, err := lh.VisitsUseCase.FetchDevicesStat(ctx, int64(idParam), int64(limit))
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, ResponseStatArray{Message: "ok", Data: stat})
+}
+
+func (lh *LinkHandler) FetchVariants(c echo.Context) error {
+	idParam, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	variants, err := lh.VariantsUseCase.FetchWithVisitsByLinkId(ctx, int64(idParam))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseVariantArray{Message: "ok", Data: variants})
+}
+
+func (lh *LinkHandler) FetchTopReferrers(c echo.Context) error {
+	idParam, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	ctx := c.Request().Context()
+
+	stat, err := lh.ReferrersUseCase.FetchTopByLinkId(ctx, int64(idParam), int64(limit))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseStatArray{Message: "ok", Data: stat})
+}
+
+// aliasLength returns the length of the generated alias: the requested one, the domain's or "alias_length"
+func aliasLength(requested int, d domain.Domain) int {
+	if requested > 0 {
+		return requested
+	}
+
+	if d.AliasLength > 0 {
+		return d.AliasLength
+	}
+
+	if length := viper.GetInt("alias_length"); length > 0 {
+		return length
+	}
+
+	return defaultAliasLength
+}
+
+func toLinkResponse(link domain.Link, tags []domain.Tags) domain.LinkResponse {
+	res := domain.LinkResponse{
+		ID:              link.ID,
+		DomainId:        link.DomainId,
+		Alias:           link.Alias,
+		Target:          link.Target,
+		Description:     link.Description.String,
+		RedirectType:    link.RedirectType,
+		MaxVisits:       link.MaxVisits.Int64,
+		VisitsCount:     link.VisitsCount,
+		State:           link.State(time.Now()),
+		Status:          link.LifecycleStatus(time.Now()),
+		Protected:       link.PasswordHash.Valid,
+		CreatedAt:       link.CreatedAt,
+		Tags:            tags,
+		Variants:        link.Variants,
+		IosUrl:          link.IosUrl.String,
+		IosStoreUrl:     link.IosStoreUrl.String,
+		AndroidUrl:      link.AndroidUrl.String,
+		AndroidStoreUrl: link.AndroidStoreUrl.String,
+		QueryPolicy:     link.QueryPolicy,
+		ForcePreview:    link.ForcePreview,
+	}
+
+	if link.ExpiresAt.Valid {
+		res.ExpiresAt = &link.ExpiresAt.Time
+	}
+
+	if link.ActivateAt.Valid {
+		res.ActivateAt = &link.ActivateAt.Time
+	}
+
+	if link.DeletedAt.Valid {
+		res.DeletedAt = &link.DeletedAt.Time
+	}
+
+	if link.DeactivateAt.Valid {
+		res.DeactivateAt = &link.DeactivateAt.Time
+	}
+
+	if link.QueryAllowlist.Valid {
+		res.QueryAllowlist = strings.Split(link.QueryAllowlist.String, ",")
+	}
+
+	if link.UtmParams.Valid {
+		if utm, err := domain.ParseLinkUtm(link.UtmParams.String); err == nil {
+			res.Utm = &utm
+		}
+	}
+
+	return res
+}
+
+// passQuery merges the link's UTM defaults and the visitor's query parameters allowed by the
+// link's policy, or by "redirect.query_policy" when the link has none, into the target
+func passQuery(c echo.Context, link domain.Link, target string) (string, error) {
+	policy := link.QueryPolicy
+	allowlist := strings.Split(link.QueryAllowlist.String, ",")
+	if policy == "" {
+		policy = viper.GetString("redirect.query_policy")
+		allowlist = viper.GetStringSlice("redirect.query_allowlist")
+	}
+
+	defaults := url.Values{}
+	if link.UtmParams.Valid {
+		utm, err := domain.ParseLinkUtm(link.UtmParams.String)
+		if err != nil {
+			return target, err
+		}
+		defaults = utm.Values()
+	}
+
+	merged, err := query.Merge(target, defaults, query.Filter(c.QueryParams(), policy, allowlist))
+	if err != nil {
+		return target, err
+	}
+
+	return merged, nil
+}
+
+func utmParams(utm *domain.LinkUtm) sql.NullString {
+	if utm == nil {
+		return sql.NullString{}
+	}
+
+	return nullString(utm.Values().Encode())
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// redirectStatus returns the redirect status of the link, falling back to the status of its domain
+// and then to "redirect.default_status"
+func redirectStatus(link domain.Link, d domain.Domain) int {
+	if isRedirectStatus(link.RedirectType) {
+		return link.RedirectType
+	}
+
+	if isRedirectStatus(d.RedirectStatus) {
+		return d.RedirectStatus
+	}
+
+	if status := viper.GetInt("redirect.default_status"); isRedirectStatus(status) {
+		return status
+	}
+
+	return http.StatusMovedPermanently
+}
+
+func isRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
 }
 
 func getStatusCode(err error) int {
@@ -208,13 +1576,47 @@ func getStatusCode(err error) int {
 		return http.StatusConflict
 	case domain.ErrLinkIsExists:
 		return http.StatusConflict
+	case domain.ErrBadParamInput:
+		return http.StatusBadRequest
+	case domain.ErrPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case domain.ErrAliasReserved:
+		return http.StatusUnprocessableEntity
+	case domain.ErrLinkExpired:
+		return http.StatusGone
+	case domain.ErrLinkNotActive:
+		return http.StatusNotFound
+	case domain.ErrLinkDeleted:
+		return http.StatusGone
+	case domain.ErrLinkDisabled:
+		return http.StatusNotFound
+	case domain.ErrInvalidPassword:
+		return http.StatusUnauthorized
+	case domain.ErrTooManyAttempts:
+		return http.StatusTooManyRequests
+	case domain.ErrQueueFull, domain.ErrWriterClosed:
+		return http.StatusServiceUnavailable
+	case domain.ErrIdempotencyKeyReused:
+		return http.StatusUnprocessableEntity
+	case domain.ErrIdempotencyInProgress:
+		return http.StatusConflict
 	default:
 		return http.StatusInternalServerError
 	}
 }
 
-func isRequestValid(m *domain.LinkRequest) (bool, error) {
+func isRequestValid(m interface{}) (bool, error) {
 	validate := validator.New()
+	_ = validate.RegisterValidation("alias", func(fl validator.FieldLevel) bool {
+		return domain.IsValidAlias(fl.Field().String())
+	})
+	_ = validate.RegisterValidation("weburl", func(fl validator.FieldLevel) bool {
+		return domain.IsWebUrl(fl.Field().String())
+	})
+	_ = validate.RegisterValidation("appurl", func(fl validator.FieldLevel) bool {
+		return domain.IsAppUrl(fl.Field().String())
+	})
+
 	err := validate.Struct(m)
 	if err != nil {
 		return false, err
@@ -222,17 +1624,74 @@ func isRequestValid(m *domain.LinkRequest) (bool, error) {
 	return true, nil
 }
 
-func (lh *LinkHandler) createAndAttachTags(ctx context.Context, linkId int64, tags []string) {
-	for _, tag := range tags {
-		t, err := lh.TagsUseCase.FirstOrCreate(ctx, domain.Tags{Name: tag})
-		if err == nil {
-			_, err = lh.LinkTagUseCase.Store(ctx, domain.LinkTag{TagId: t, LinkId: linkId})
-			if err != nil {
-				logrus.Error(err)
-				continue
-			}
-		} else {
-			logrus.Error(err)
+// newVisit collects the visit data of the current request
+func newVisit(c echo.Context, linkId int64, trustProxy bool) domain.Visits {
+	req := c.Request()
+
+	headers := make(map[string]string)
+	for _, name := range visitHeaders {
+		if value := req.Header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+
+	encodedHeaders, err := json.Marshal(headers)
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	return domain.Visits{
+		LinkId:      linkId,
+		Ip:          ipToInt(clientIP(c, trustProxy)),
+		Headers:     string(encodedHeaders),
+		QueryString: req.URL.RawQuery,
+		UserAgent:   req.UserAgent(),
+		Referrer:    req.Referer(),
+	}
+}
+
+// visitHeaders are the request headers stored with the visit, other headers may carry credentials
+// like Authorization, Cookie or X-Link-Password and are never stored
+var visitHeaders = []string{
+	echo.HeaderAccept,
+	echo.HeaderAcceptEncoding,
+	"Accept-Language",
+	"Dnt",
+	"Referer",
+	"Sec-Ch-Ua",
+	"Sec-Ch-Ua-Mobile",
+	"Sec-Ch-Ua-Platform",
+	"User-Agent",
+	echo.HeaderXForwardedFor,
+	echo.HeaderXRealIP,
+}
+
+// ipToInt converts IPv4 address to its numeric form, other addresses are stored as 0
+func ipToInt(ip string) int {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		return 0
+	}
+
+	return int(binary.BigEndian.Uint32(parsed))
+}
+
+// tagIds returns the ids of the named tags, creating the missing ones
+func (lh *LinkHandler) tagIds(ctx context.Context, names []string) ([]int64, error) {
+	ids := make([]int64, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
 		}
+		seen[name] = true
+
+		id, err := lh.TagsUseCase.FirstOrCreate(ctx, domain.Tags{Name: name})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
 	}
+
+	return ids, nil
 }
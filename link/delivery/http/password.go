@@ -0,0 +1,128 @@
+package http
+
+import (
+	"github.com/labstack/echo"
+	"html/template"
+	"net"
+	"sync"
+	"time"
+)
+
+// HeaderLinkPassword lets API clients open a protected link without the form
+const HeaderLinkPassword = "X-Link-Password"
+
+var passwordTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<meta name="robots" content="noindex">
+	<title>Protected link</title>
+</head>
+<body>
+	<form method="post" action="/{{.Alias}}">
+		<p>This link is protected with a password.</p>
+		{{if .Error}}<p style="color: #c00">{{.Error}}</p>{{end}}
+		<input type="password" name="password" autofocus required>
+		<button type="submit">Continue</button>
+	</form>
+</body>
+</html>
+`))
+
+type passwordPage struct {
+	Alias string
+	Error string
+}
+
+// attemptLimiter counts failed password attempts per key within a fixed window
+type attemptLimiter struct {
+	maxAttempts int
+	window      time.Duration
+
+	mu        sync.Mutex
+	attempts  map[string]attempts
+	nextSweep time.Time
+}
+
+type attempts struct {
+	count   int
+	resetAt time.Time
+}
+
+func newAttemptLimiter(maxAttempts int, window time.Duration) *attemptLimiter {
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+
+	return &attemptLimiter{maxAttempts: maxAttempts, window: window, attempts: make(map[string]attempts)}
+}
+
+// Allow reports whether the key has attempts left in the current window
+func (l *attemptLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a, ok := l.attempts[key]
+	if !ok {
+		return true
+	}
+
+	if time.Now().After(a.resetAt) {
+		delete(l.attempts, key)
+		return true
+	}
+
+	return a.count < l.maxAttempts
+}
+
+// Fail records a failed attempt of the key
+func (l *attemptLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	a, ok := l.attempts[key]
+	if !ok || now.After(a.resetAt) {
+		a = attempts{resetAt: now.Add(l.window)}
+	}
+
+	a.count++
+	l.attempts[key] = a
+
+	// drop the expired windows once per window, so the map only holds the keys of the current one
+	if now.After(l.nextSweep) {
+		for k, v := range l.attempts {
+			if now.After(v.resetAt) {
+				delete(l.attempts, k)
+			}
+		}
+		l.nextSweep = now.Add(l.window)
+	}
+}
+
+// Reset forgets the failed attempts of the key
+func (l *attemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
+
+// clientIP returns the address of the visitor. X-Forwarded-For and X-Real-IP are set by the client
+// unless a proxy overwrites them, so they are only used when the proxy is trusted.
+func clientIP(c echo.Context, trustProxy bool) string {
+	if trustProxy {
+		return c.RealIP()
+	}
+
+	host, _, err := net.SplitHostPort(c.Request().RemoteAddr)
+	if err != nil {
+		return c.Request().RemoteAddr
+	}
+
+	return host
+}
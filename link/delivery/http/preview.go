@@ -0,0 +1,50 @@
+package http
+
+import (
+	"bytes"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+	"html/template"
+	"time"
+)
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<meta name="robots" content="noindex">
+	<title>Link preview</title>
+</head>
+<body>
+	<h1>You are about to leave to</h1>
+	{{if .Protected}}
+	<p>The destination of this link is protected with a password.</p>
+	{{else}}
+	<p><code>{{.Target}}</code></p>
+	{{end}}
+	{{if .Description}}<p>{{.Description}}</p>{{end}}
+	{{if .Tags}}<p>{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t.Name}}{{end}}</p>{{end}}
+	<p>Created {{.CreatedAt.Format "2006-01-02"}}</p>
+	<p><a href="{{.ContinueUrl}}">Continue</a></p>
+</body>
+</html>
+`))
+
+type previewPage struct {
+	Target      string
+	Description string
+	Tags        []domain.Tags
+	CreatedAt   time.Time
+	Protected   bool
+	ContinueUrl string
+}
+
+func renderPreviewPage(page previewPage) string {
+	var buf bytes.Buffer
+	if err := previewTemplate.Execute(&buf, page); err != nil {
+		logrus.Error(err)
+	}
+
+	return buf.String()
+}
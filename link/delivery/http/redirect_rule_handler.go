@@ -0,0 +1,166 @@
+package http
+
+import (
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/labstack/echo"
+	"net/http"
+	"strconv"
+)
+
+type ResponseRuleObject struct {
+	Message string              `json:"message"`
+	Data    domain.RedirectRule `json:"data"`
+}
+
+type ResponseRuleArray struct {
+	Message string                `json:"message"`
+	Data    []domain.RedirectRule `json:"data"`
+}
+
+type RedirectRuleHandler struct {
+	RulesUseCase domain.RedirectRulesUseCase
+	LUseCase     domain.LinkUseCase
+}
+
+func NewRedirectRuleHandler(e *echo.Echo, rulesUcase domain.RedirectRulesUseCase, us domain.LinkUseCase) {
+	handler := &RedirectRuleHandler{
+		RulesUseCase: rulesUcase,
+		LUseCase:     us,
+	}
+
+	e.GET("/links/:id/rules", handler.FetchRules)
+	e.POST("/links/:id/rules", handler.StoreRule)
+	e.PUT("/links/:id/rules/:ruleId", handler.UpdateRule)
+	e.DELETE("/links/:id/rules/:ruleId", handler.DeleteRule)
+}
+
+func (rh *RedirectRuleHandler) FetchRules(c echo.Context) error {
+	linkId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	list, err := rh.RulesUseCase.FetchByLinkId(ctx, int64(linkId))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseRuleArray{Message: "ok", Data: list})
+}
+
+func (rh *RedirectRuleHandler) StoreRule(c echo.Context) error {
+	linkId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	var req domain.RedirectRuleRequest
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	var ok bool
+	if ok, err = isRequestValid(&req); !ok {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	_, err = rh.LUseCase.GetById(ctx, int64(linkId))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	id, err := rh.RulesUseCase.Store(ctx, domain.RedirectRule{
+		LinkId:    int64(linkId),
+		Position:  req.Position,
+		Condition: req.Condition,
+		Target:    req.Target,
+	})
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	rule, err := rh.RulesUseCase.GetById(ctx, id)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, ResponseRuleObject{Message: "ok", Data: rule})
+}
+
+func (rh *RedirectRuleHandler) UpdateRule(c echo.Context) error {
+	linkId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	ruleId, err := strconv.Atoi(c.Param("ruleId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	var req domain.RedirectRuleRequest
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	var ok bool
+	if ok, err = isRequestValid(&req); !ok {
+		return c.JSON(http.StatusUnprocessableEntity, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	_, err = rh.RulesUseCase.Update(ctx, domain.RedirectRule{
+		ID:        int64(ruleId),
+		LinkId:    int64(linkId),
+		Position:  req.Position,
+		Condition: req.Condition,
+		Target:    req.Target,
+	})
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	rule, err := rh.RulesUseCase.GetById(ctx, int64(ruleId))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ResponseRuleObject{Message: "ok", Data: rule})
+}
+
+func (rh *RedirectRuleHandler) DeleteRule(c echo.Context) error {
+	linkId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	ruleId, err := strconv.Atoi(c.Param("ruleId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: err.Error()})
+	}
+
+	ctx := c.Request().Context()
+
+	rule, err := rh.RulesUseCase.GetById(ctx, int64(ruleId))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	if rule.LinkId != int64(linkId) {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: domain.ErrNotFound.Error()})
+	}
+
+	err = rh.RulesUseCase.Delete(ctx, rule.ID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
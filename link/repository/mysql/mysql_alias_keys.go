@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+type mysqlAliasKeysRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlAliasKeysRepository(conn *sql.DB) domain.AliasKeysRepository {
+	return &mysqlAliasKeysRepository{Conn: conn}
+}
+
+// Claim keeps the claimed aliases in the table as tombstones, so they are never inserted again
+func (m *mysqlAliasKeysRepository) Claim(ctx context.Context, n int) (aliases []string, err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `SELECT alias FROM alias_keys WHERE claimed_at IS NULL
+				LIMIT ? FOR UPDATE SKIP LOCKED`, n)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases = make([]string, 0, n)
+	for rows.Next() {
+		var alias string
+		if err = rows.Scan(&alias); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(aliases) == 0 {
+		return aliases, tx.Commit()
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(aliases)), ", ")
+	args := make([]interface{}, 0, len(aliases)+1)
+	args = append(args, time.Now())
+	for _, alias := range aliases {
+		args = append(args, alias)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE alias_keys SET claimed_at = ? WHERE alias IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return aliases, tx.Commit()
+}
+
+// Insert skips the aliases which are already used by a link in any domain
+func (m *mysqlAliasKeysRepository) Insert(ctx context.Context, aliases []string) (int64, error) {
+	if len(aliases) == 0 {
+		return 0, nil
+	}
+
+	used, err := m.usedByLinks(ctx, aliases)
+	if err != nil {
+		return 0, err
+	}
+
+	args := make([]interface{}, 0, len(aliases))
+	for _, alias := range aliases {
+		if !used[strings.ToLower(alias)] {
+			args = append(args, alias)
+		}
+	}
+
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("(?), ", len(args)), ", ")
+
+	res, err := m.Conn.ExecContext(ctx, `INSERT IGNORE INTO alias_keys (alias) VALUES `+placeholders, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// usedByLinks returns the lowercased aliases which are the aliases of links
+func (m *mysqlAliasKeysRepository) usedByLinks(ctx context.Context, aliases []string) (map[string]bool, error) {
+	args := make([]interface{}, 0, len(aliases))
+	for _, alias := range aliases {
+		args = append(args, alias)
+	}
+
+	rows, err := m.Conn.QueryContext(ctx, `SELECT alias FROM link WHERE alias IN (`+
+		strings.TrimSuffix(strings.Repeat("?, ", len(aliases)), ", ")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if errRow := rows.Close(); errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	used := make(map[string]bool)
+	for rows.Next() {
+		var alias string
+		if err = rows.Scan(&alias); err != nil {
+			return nil, err
+		}
+		// the alias column may compare case-insensitively
+		used[strings.ToLower(alias)] = true
+	}
+
+	return used, rows.Err()
+}
+
+func (m *mysqlAliasKeysRepository) CountUnclaimed(ctx context.Context) (int64, error) {
+	var count int64
+
+	err := m.Conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM alias_keys WHERE claimed_at IS NULL`).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,126 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type mysqlBrowsersRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlBrowsersRepository(conn *sql.DB) domain.BrowsersRepository {
+	return &mysqlBrowsersRepository{Conn: conn}
+}
+
+func (m *mysqlBrowsersRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Browsers, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Browsers, 0)
+	for rows.Next() {
+		t := domain.Browsers{}
+		err = rows.Scan(
+			&t.ID,
+			&t.Name,
+			&t.Version,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlBrowsersRepository) GetById(ctx context.Context, id int64) (domain.Browsers, error) {
+	query := `SELECT id, name, version, created_at, updated_at
+				FROM browsers where id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+
+	if err != nil {
+		return domain.Browsers{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.Browsers{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlBrowsersRepository) GetByNameAndVersion(ctx context.Context, name string, version string) (domain.Browsers, error) {
+	query := `SELECT id, name, version, created_at, updated_at
+				FROM browsers where name = ? AND version = ?`
+
+	list, err := m.fetch(ctx, query, name, version)
+
+	if err != nil {
+		return domain.Browsers{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.Browsers{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlBrowsersRepository) FirstOrCreate(ctx context.Context, browser domain.Browsers) (int64, error) {
+	existed, err := m.GetByNameAndVersion(ctx, browser.Name, browser.Version)
+	if err == nil {
+		return existed.ID, nil
+	}
+
+	query := `INSERT browsers SET name = ?, version = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, browser.Name, browser.Version)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 { // created by a concurrent request
+			existed, err = m.GetByNameAndVersion(ctx, browser.Name, browser.Version)
+			if err != nil {
+				return 0, err
+			}
+
+			return existed.ID, nil
+		}
+
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected != 1 {
+		return 0, fmt.Errorf("Total affected: %d", rowsAffected)
+	}
+
+	return res.LastInsertId()
+}
@@ -0,0 +1,125 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type mysqlDevicesRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlDevicesRepository(conn *sql.DB) domain.DevicesRepository {
+	return &mysqlDevicesRepository{Conn: conn}
+}
+
+func (m *mysqlDevicesRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Devices, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Devices, 0)
+	for rows.Next() {
+		t := domain.Devices{}
+		err = rows.Scan(
+			&t.ID,
+			&t.Name,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlDevicesRepository) GetById(ctx context.Context, id int64) (domain.Devices, error) {
+	query := `SELECT id, name, created_at, updated_at
+				FROM devices where id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+
+	if err != nil {
+		return domain.Devices{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.Devices{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlDevicesRepository) GetByName(ctx context.Context, name string) (domain.Devices, error) {
+	query := `SELECT id, name, created_at, updated_at
+				FROM devices where name = ?`
+
+	list, err := m.fetch(ctx, query, name)
+
+	if err != nil {
+		return domain.Devices{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.Devices{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlDevicesRepository) FirstOrCreate(ctx context.Context, device domain.Devices) (int64, error) {
+	existed, err := m.GetByName(ctx, device.Name)
+	if err == nil {
+		return existed.ID, nil
+	}
+
+	query := `INSERT devices SET name = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, device.Name)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 { // created by a concurrent request
+			existed, err = m.GetByName(ctx, device.Name)
+			if err != nil {
+				return 0, err
+			}
+
+			return existed.ID, nil
+		}
+
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected != 1 {
+		return 0, fmt.Errorf("Total affected: %d", rowsAffected)
+	}
+
+	return res.LastInsertId()
+}
@@ -0,0 +1,174 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type mysqlDomainsRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlDomainsRepository(conn *sql.DB) domain.DomainsRepository {
+	return &mysqlDomainsRepository{Conn: conn}
+}
+
+func (m *mysqlDomainsRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Domain, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Domain, 0)
+	for rows.Next() {
+		t := domain.Domain{}
+		err = rows.Scan(
+			&t.ID,
+			&t.Host,
+			&t.DefaultRedirect,
+			&t.NotFoundPage,
+			&t.AliasLength,
+			&t.RedirectStatus,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlDomainsRepository) Fetch(ctx context.Context, limit int64) ([]domain.Domain, error) {
+	query := `SELECT id, host, default_redirect, not_found_page, alias_length, redirect_status, created_at, updated_at
+				FROM domains ORDER BY created_at LIMIT ?`
+
+	return m.fetch(ctx, query, limit)
+}
+
+func (m *mysqlDomainsRepository) GetById(ctx context.Context, id int64) (domain.Domain, error) {
+	query := `SELECT id, host, default_redirect, not_found_page, alias_length, redirect_status, created_at, updated_at
+				FROM domains WHERE id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+	if err != nil {
+		return domain.Domain{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	}
+
+	return domain.Domain{}, domain.ErrNotFound
+}
+
+func (m *mysqlDomainsRepository) GetByHost(ctx context.Context, host string) (domain.Domain, error) {
+	query := `SELECT id, host, default_redirect, not_found_page, alias_length, redirect_status, created_at, updated_at
+				FROM domains WHERE host = ?`
+
+	list, err := m.fetch(ctx, query, host)
+	if err != nil {
+		return domain.Domain{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	}
+
+	return domain.Domain{}, domain.ErrNotFound
+}
+
+func (m *mysqlDomainsRepository) Update(ctx context.Context, d domain.Domain) (int64, error) {
+	query := `UPDATE domains SET host = ?, default_redirect = ?, not_found_page = ?, alias_length = ?, redirect_status = ?,
+				updated_at = ? WHERE id = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, d.Host, d.DefaultRedirect, d.NotFoundPage, d.AliasLength, d.RedirectStatus, d.UpdatedAt, d.ID)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return 0, domain.ErrConflict
+		}
+
+		return 0, err
+	}
+
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if affect != 1 {
+		return 0, fmt.Errorf("Total Affected: %d", affect)
+	}
+
+	return d.ID, nil
+}
+
+func (m *mysqlDomainsRepository) Store(ctx context.Context, d domain.Domain) (int64, error) {
+	query := `INSERT domains SET host = ?, default_redirect = ?, not_found_page = ?, alias_length = ?, redirect_status = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, d.Host, d.DefaultRedirect, d.NotFoundPage, d.AliasLength, d.RedirectStatus)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return 0, domain.ErrConflict
+		}
+
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected != 1 {
+		return 0, fmt.Errorf("Total affected: %d", rowsAffected)
+	}
+
+	return res.LastInsertId()
+}
+
+func (m *mysqlDomainsRepository) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM domains WHERE id = ?`
+
+	res, err := m.Conn.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return fmt.Errorf("Total Affected: %d", rowsAffected)
+	}
+
+	return nil
+}
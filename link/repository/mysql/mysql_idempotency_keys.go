@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type mysqlIdempotencyKeysRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlIdempotencyKeysRepository(conn *sql.DB) domain.IdempotencyKeysRepository {
+	return &mysqlIdempotencyKeysRepository{Conn: conn}
+}
+
+func (m *mysqlIdempotencyKeysRepository) GetByKey(ctx context.Context, key string) (domain.IdempotencyKey, error) {
+	query := `SELECT idempotency_key, fingerprint, status_code, response, created_at, expires_at
+				FROM idempotency_keys WHERE idempotency_key = ? AND expires_at > ?`
+
+	var k domain.IdempotencyKey
+	err := m.Conn.QueryRowContext(ctx, query, key, time.Now()).Scan(
+		&k.Key,
+		&k.Fingerprint,
+		&k.StatusCode,
+		&k.Response,
+		&k.CreatedAt,
+		&k.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return domain.IdempotencyKey{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.IdempotencyKey{}, err
+	}
+
+	return k, nil
+}
+
+// Reserve drops the expired row of the key first, so an expired key or a pending key whose lease
+// ran out can be used again
+func (m *mysqlIdempotencyKeysRepository) Reserve(ctx context.Context, key domain.IdempotencyKey) error {
+	_, err := m.Conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE idempotency_key = ? AND expires_at <= ?`,
+		key.Key, time.Now())
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT idempotency_keys SET idempotency_key = ?, fingerprint = ?, owner = ?, status_code = 0, expires_at = ?`
+
+	_, err = m.Conn.ExecContext(ctx, query, key.Key, key.Fingerprint, key.Owner, key.ExpiresAt)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return domain.ErrConflict
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Complete and Delete only change the row of the owner's reservation, a reservation taken over
+// after its lease ran out belongs to the new request
+func (m *mysqlIdempotencyKeysRepository) Complete(ctx context.Context, key string, owner string, statusCode int,
+	response []byte, expiresAt time.Time) error {
+	query := `UPDATE idempotency_keys SET status_code = ?, response = ?, expires_at = ?
+				WHERE idempotency_key = ? AND owner = ? AND status_code = 0`
+
+	res, err := m.Conn.ExecContext(ctx, query, statusCode, response, expiresAt, key, owner)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return fmt.Errorf("Total Affected: %d", rowsAffected)
+	}
+
+	return nil
+}
+
+func (m *mysqlIdempotencyKeysRepository) Delete(ctx context.Context, key string, owner string) error {
+	_, err := m.Conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE idempotency_key = ? AND owner = ?`, key, owner)
+
+	return err
+}
+
+func (m *mysqlIdempotencyKeysRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := m.Conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
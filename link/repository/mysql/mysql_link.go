@@ -6,10 +6,37 @@ import (
 	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/random"
 	"github.com/sirupsen/logrus"
+	"strings"
 	"time"
 )
 
+const linkColumns = `id, user_id, domain_id, alias, target, target_hash, description, redirect_type, expires_at, max_visits, visits_count,
+				activate_at, deactivate_at, password_hash, ios_url, ios_store_url, android_url, android_store_url,
+				query_policy, query_allowlist, utm_params, force_preview, status, created_at, updated_at, deleted_at`
+
+// linkAssignments are the columns written by both Store and Update, in the order of linkAssignmentArgs
+const linkAssignments = `domain_id = ?, alias = ?, target = ?, target_hash = ?, user_id = ?, description = ?, redirect_type = ?, expires_at = ?,
+				max_visits = ?, activate_at = ?, deactivate_at = ?, password_hash = ?, ios_url = ?, ios_store_url = ?,
+				android_url = ?, android_store_url = ?, query_policy = ?, query_allowlist = ?, utm_params = ?,
+				force_preview = ?, status = ?`
+
+func linkAssignmentArgs(link domain.Link) []interface{} {
+	return []interface{}{
+		link.DomainId, link.Alias, link.Target, link.TargetHash, link.UserId, link.Description, link.RedirectType, link.ExpiresAt,
+		link.MaxVisits, link.ActivateAt, link.DeactivateAt, link.PasswordHash, link.IosUrl, link.IosStoreUrl,
+		link.AndroidUrl, link.AndroidStoreUrl, link.QueryPolicy, link.QueryAllowlist, link.UtmParams,
+		link.ForcePreview, link.Status,
+	}
+}
+
+// linkInsertColumns and linkInsertValues are linkAssignments as the column list and the row of a multi-row insert
+var (
+	linkInsertColumns = strings.ReplaceAll(linkAssignments, " = ?", "")
+	linkInsertValues  = "(" + strings.TrimSuffix(strings.Repeat("?, ", len(linkAssignmentArgs(domain.Link{}))), ", ") + ")"
+)
+
 type mysqlLinkRepository struct {
 	Conn *sql.DB
 }
@@ -38,11 +65,29 @@ func (m *mysqlLinkRepository) fetch(ctx context.Context, query string, args ...i
 		err = rows.Scan(
 			&t.ID,
 			&t.UserId,
+			&t.DomainId,
 			&t.Alias,
 			&t.Target,
+			&t.TargetHash,
 			&t.Description,
-			&t.UpdatedAt,
+			&t.RedirectType,
+			&t.ExpiresAt,
+			&t.MaxVisits,
+			&t.VisitsCount,
+			&t.ActivateAt,
+			&t.DeactivateAt,
+			&t.PasswordHash,
+			&t.IosUrl,
+			&t.IosStoreUrl,
+			&t.AndroidUrl,
+			&t.AndroidStoreUrl,
+			&t.QueryPolicy,
+			&t.QueryAllowlist,
+			&t.UtmParams,
+			&t.ForcePreview,
+			&t.Status,
 			&t.CreatedAt,
+			&t.UpdatedAt,
 			&t.DeletedAt,
 		)
 
@@ -56,11 +101,48 @@ func (m *mysqlLinkRepository) fetch(ctx context.Context, query string, args ...i
 	return result, nil
 }
 
-func (m *mysqlLinkRepository) Fetch(ctx context.Context, limit int64) ([]domain.Link, error) {
-	query := `SELECT id, user_id, alias, target, description, created_at, updated_at, deleted_at
-				FROM link ORDER BY created_at LIMIT ?`
+const (
+	linkEndedCondition = `((deactivate_at IS NOT NULL AND deactivate_at <= ?) OR (expires_at IS NOT NULL AND expires_at <= ?)
+				OR (max_visits IS NOT NULL AND visits_count >= max_visits))`
+	linkScheduledCondition = `(activate_at IS NOT NULL AND activate_at > ?)`
+)
+
+// linkFilterWhere returns the conditions of the filter with their arguments
+func linkFilterWhere(filter domain.LinkFilter) (string, []interface{}) {
+	where := `deleted_at IS NULL`
+	if filter.Deleted {
+		where = `deleted_at IS NOT NULL`
+	}
+	args := make([]interface{}, 0)
+
+	now := time.Now()
+	if filter.Status != "" {
+		where += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+
+	switch filter.State {
+	case domain.LinkStateEnded:
+		where += ` AND ` + linkEndedCondition
+		args = append(args, now, now)
+	case domain.LinkStateScheduled:
+		where += ` AND NOT ` + linkEndedCondition + ` AND ` + linkScheduledCondition
+		args = append(args, now, now, now)
+	case domain.LinkStateLive:
+		where += ` AND NOT ` + linkEndedCondition + ` AND NOT ` + linkScheduledCondition
+		args = append(args, now, now, now)
+	}
+
+	return where, args
+}
+
+func (m *mysqlLinkRepository) Fetch(ctx context.Context, filter domain.LinkFilter) ([]domain.Link, error) {
+	where, args := linkFilterWhere(filter)
+
+	query := `SELECT ` + linkColumns + `
+				FROM link WHERE ` + where + ` ORDER BY created_at LIMIT ?`
 
-	res, err := m.fetch(ctx, query, limit)
+	res, err := m.fetch(ctx, query, append(args, filter.Limit)...)
 
 	if err != nil {
 		return nil, err
@@ -69,8 +151,17 @@ func (m *mysqlLinkRepository) Fetch(ctx context.Context, limit int64) ([]domain.
 	return res, nil
 }
 
+func (m *mysqlLinkRepository) FetchAfter(ctx context.Context, filter domain.LinkFilter, afterId int64) ([]domain.Link, error) {
+	where, args := linkFilterWhere(filter)
+
+	query := `SELECT ` + linkColumns + `
+				FROM link WHERE ` + where + ` AND id > ? ORDER BY id LIMIT ?`
+
+	return m.fetch(ctx, query, append(args, afterId, filter.Limit)...)
+}
+
 func (m *mysqlLinkRepository) GetById(ctx context.Context, id int64) (domain.Link, error) {
-	query := `SELECT id, user_id, alias, target, description, created_at, updated_at, deleted_at
+	query := `SELECT ` + linkColumns + `
 				FROM link where id = ?`
 
 	list, err := m.fetch(ctx, query, id)
@@ -87,7 +178,7 @@ func (m *mysqlLinkRepository) GetById(ctx context.Context, id int64) (domain.Lin
 }
 
 func (m *mysqlLinkRepository) Update(ctx context.Context, link domain.Link) (int64, error) {
-	query := `UPDATE link SET alias = ?, target = ?, user_id =?, deleted_at = ?, updated_at = ?, description=? WHERE id = ?`
+	query := `UPDATE link SET ` + linkAssignments + `, deleted_at = ?, updated_at = ? WHERE id = ?`
 
 	stmt, err := m.Conn.PrepareContext(ctx, query)
 
@@ -95,7 +186,8 @@ func (m *mysqlLinkRepository) Update(ctx context.Context, link domain.Link) (int
 		return 0, err
 	}
 
-	res, err := stmt.ExecContext(ctx, link.Alias, link.Target, link.UserId, link.DeletedAt, link.UpdatedAt, link.Description, link.ID)
+	args := append(linkAssignmentArgs(link), link.DeletedAt, link.UpdatedAt, link.ID)
+	res, err := stmt.ExecContext(ctx, args...)
 
 	if err != nil {
 		return 0, err
@@ -114,11 +206,89 @@ func (m *mysqlLinkRepository) Update(ctx context.Context, link domain.Link) (int
 	return link.ID, nil
 }
 
-func (m *mysqlLinkRepository) GetByAlias(ctx context.Context, alias string) (domain.Link, error) {
-	query := `SELECT id, user_id, alias, target, description, created_at, updated_at, deleted_at
-				FROM link where alias = ?`
+// UpdateIfUnmodified makes the updated_at read with the link the condition of the update, so a concurrent
+// update in between fails with ErrPreconditionFailed instead of being overwritten
+func (m *mysqlLinkRepository) UpdateIfUnmodified(ctx context.Context, update domain.LinkUpdate,
+	unmodifiedSince time.Time) (err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	link := update.Link
+	query := `UPDATE link SET ` + linkAssignments + `, updated_at = ?
+				WHERE id = ? AND updated_at = ? AND deleted_at IS NULL`
+
+	args := append(linkAssignmentArgs(link), link.UpdatedAt, link.ID, unmodifiedSince)
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return domain.ErrLinkIsExists
+		}
+
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrPreconditionFailed
+	}
+
+	if update.ReplaceVariants {
+		if err = replaceVariants(ctx, tx, link.ID, link.Variants); err != nil {
+			return err
+		}
+	}
+
+	// the tags which are kept stay attached, so only the removed ones are deleted
+	tagArgs := make([]interface{}, 0, len(update.TagIds)+1)
+	tagArgs = append(tagArgs, link.ID)
+	query = `DELETE FROM link_tag WHERE link_id = ?`
+	if len(update.TagIds) > 0 {
+		for _, id := range update.TagIds {
+			tagArgs = append(tagArgs, id)
+		}
+		query += ` AND tag_id NOT IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(update.TagIds)), ", ") + `)`
+	}
+
+	if _, err = tx.ExecContext(ctx, query, tagArgs...); err != nil {
+		return err
+	}
+
+	if len(update.TagIds) > 0 {
+		query = `INSERT IGNORE INTO link_tag (link_id, tag_id) VALUES ` +
+			strings.TrimSuffix(strings.Repeat("(?, ?), ", len(update.TagIds)), ", ")
+
+		values := make([]interface{}, 0, len(update.TagIds)*2)
+		for _, id := range update.TagIds {
+			values = append(values, link.ID, id)
+		}
+
+		if _, err = tx.ExecContext(ctx, query, values...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *mysqlLinkRepository) GetByAlias(ctx context.Context, domainId int64, alias string) (domain.Link, error) {
+	query := `SELECT ` + linkColumns + `
+				FROM link where domain_id = ? AND alias = ?`
 
-	list, err := m.fetch(ctx, query, alias)
+	list, err := m.fetch(ctx, query, domainId, alias)
 
 	if err != nil {
 		return domain.Link{}, err
@@ -132,17 +302,16 @@ func (m *mysqlLinkRepository) GetByAlias(ctx context.Context, alias string) (dom
 }
 
 func (m *mysqlLinkRepository) Store(ctx context.Context, link domain.Link) (int64, error) {
-	query := `INSERT link SET alias = ?, target = ?, user_id = ?`
+	query := `INSERT link SET ` + linkAssignments
 
 	stmt, err := m.Conn.PrepareContext(ctx, query)
 	if err != nil {
 		return 0, err
 	}
 
-	res, err := stmt.ExecContext(ctx, link.Alias, link.Target, link.UserId)
+	res, err := stmt.ExecContext(ctx, linkAssignmentArgs(link)...)
 	if err != nil {
-		mysqlErr, _ := err.(*mysql.MySQLError)
-		if mysqlErr.Number == 1062 { // MySQL error code for "Duplicate entry"
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 { // MySQL error code for "Duplicate entry"
 			return 0, domain.ErrLinkIsExists
 		}
 
@@ -190,3 +359,393 @@ func (m *mysqlLinkRepository) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// IncrementVisits counts a visit of the link. The limit is checked in the same statement,
+// so concurrent visits can never exceed max_visits.
+func (m *mysqlLinkRepository) IncrementVisits(ctx context.Context, id int64) error {
+	query := `UPDATE link SET visits_count = visits_count + 1
+				WHERE id = ? AND (max_visits IS NULL OR visits_count < max_visits)`
+
+	res, err := m.Conn.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrLinkExpired
+	}
+
+	return nil
+}
+
+// UpdateStatus moves the link from one status to another, the link must be in the expected status and not deleted
+func (m *mysqlLinkRepository) UpdateStatus(ctx context.Context, id int64, from string, to string) error {
+	query := `UPDATE link SET status = ?, updated_at = ? WHERE id = ? AND status = ? AND deleted_at IS NULL`
+
+	res, err := m.Conn.ExecContext(ctx, query, to, time.Now(), id, from)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrConflict
+	}
+
+	return nil
+}
+
+// Restore takes the link out of the trash
+func (m *mysqlLinkRepository) Restore(ctx context.Context, id int64) error {
+	query := `UPDATE link SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+
+	res, err := m.Conn.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// Purge hard deletes the links deleted before the given time together with their tags, rules and variants.
+// Visits are kept for the statistics.
+func (m *mysqlLinkRepository) Purge(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	for _, table := range []string{"link_tag", "redirect_rules", "link_variants"} {
+		query := `DELETE t FROM ` + table + ` AS t JOIN link AS l ON l.id = t.link_id
+				WHERE l.deleted_at IS NOT NULL AND l.deleted_at < ?`
+
+		_, err = tx.ExecContext(ctx, query, deletedBefore)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM link WHERE deleted_at IS NOT NULL AND deleted_at < ?`, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err = res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, tx.Commit()
+}
+
+// StoreWithIdAlias inserts the link under a temporary alias and replaces it with the alias of the new id
+// in the same transaction, so the temporary alias is never visible.
+func (m *mysqlLinkRepository) StoreWithIdAlias(ctx context.Context, link domain.Link,
+	aliasOf func(id int64) (string, error)) (id int64, err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	link.Alias = temporaryAlias()
+
+	res, err := tx.ExecContext(ctx, `INSERT link SET `+linkAssignments, linkAssignmentArgs(link)...)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return 0, domain.ErrLinkIsExists
+		}
+
+		return 0, err
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	alias, err := aliasOf(id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE link SET alias = ? WHERE id = ?`, alias, id)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return 0, domain.ErrLinkIsExists
+		}
+
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// temporaryAlias is stored until the alias derived from the link's id is known,
+// it only has to be unique and "~" is not in the alias alphabets
+func temporaryAlias() string {
+	return "~" + random.NewRandomString(20)
+}
+
+// GetByTargetHash returns the user's newest link of the target which is not deleted or disabled
+func (m *mysqlLinkRepository) GetByTargetHash(ctx context.Context, userId int64, domainId int64, hash string) (domain.Link, error) {
+	query := `SELECT ` + linkColumns + `
+				FROM link WHERE user_id = ? AND domain_id = ? AND target_hash = ? AND deleted_at IS NULL AND status = ?
+				ORDER BY id DESC LIMIT 1`
+
+	list, err := m.fetch(ctx, query, userId, domainId, hash, domain.LinkStatusActive)
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	}
+
+	return domain.Link{}, domain.ErrNotFound
+}
+
+func (m *mysqlLinkRepository) TakenAliases(ctx context.Context, links []domain.Link) ([]bool, error) {
+	taken := make([]bool, len(links))
+	if len(links) == 0 {
+		return taken, nil
+	}
+
+	placeholders := make([]string, 0, len(links))
+	args := make([]interface{}, 0, len(links)*2)
+	for _, l := range links {
+		placeholders = append(placeholders, "(?, ?)")
+		args = append(args, l.DomainId, l.Alias)
+	}
+
+	query := `SELECT domain_id, alias FROM link WHERE (domain_id, alias) IN (` + strings.Join(placeholders, ", ") + `)`
+
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	used := make(map[string]bool)
+	for rows.Next() {
+		var domainId int64
+		var alias string
+		if err = rows.Scan(&domainId, &alias); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+
+		used[fmt.Sprintf("%d/%s", domainId, alias)] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, l := range links {
+		taken[i] = used[fmt.Sprintf("%d/%s", l.DomainId, l.Alias)]
+	}
+
+	return taken, nil
+}
+
+// StoreBatch inserts all links with a single statement. The ids of a multi-row insert are not always
+// consecutive, e.g. with innodb_autoinc_lock_mode=2, so they are read back by the unique domain and alias.
+func (m *mysqlLinkRepository) StoreBatch(ctx context.Context, items []domain.LinkBatchItem,
+	aliasOf func(id int64) (string, error)) (ids []int64, err error) {
+	if len(items) == 0 {
+		return []int64{}, nil
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	placeholders := make([]string, 0, len(items))
+	args := make([]interface{}, 0, len(items)*len(linkAssignmentArgs(domain.Link{})))
+	keys := make([]string, 0, len(items))
+	keyArgs := make([]interface{}, 0, len(items)*2)
+	derived := make([]int, 0)
+	for i, item := range items {
+		link := item.Link
+		if link.Alias == "" && aliasOf != nil {
+			link.Alias = temporaryAlias()
+			derived = append(derived, i)
+		}
+
+		placeholders = append(placeholders, linkInsertValues)
+		args = append(args, linkAssignmentArgs(link)...)
+		keys = append(keys, "(?, ?)")
+		keyArgs = append(keyArgs, link.DomainId, link.Alias)
+	}
+
+	query := `INSERT INTO link (` + linkInsertColumns + `) VALUES ` + strings.Join(placeholders, ", ")
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return nil, domain.ErrLinkIsExists
+		}
+
+		return nil, err
+	}
+
+	ids, err = m.insertedIds(ctx, tx, keys, keyArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(derived) > 0 {
+		cases := make([]string, 0, len(derived))
+		caseArgs := make([]interface{}, 0, len(derived)*3)
+		in := make([]interface{}, 0, len(derived))
+		for _, i := range derived {
+			alias, err := aliasOf(ids[i])
+			if err != nil {
+				return nil, err
+			}
+
+			cases = append(cases, "WHEN ? THEN ?")
+			caseArgs = append(caseArgs, ids[i], alias)
+			in = append(in, ids[i])
+		}
+
+		query := `UPDATE link SET alias = CASE id ` + strings.Join(cases, " ") + ` END
+				WHERE id IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(in)), ", ") + `)`
+
+		_, err = tx.ExecContext(ctx, query, append(caseArgs, in...)...)
+		if err != nil {
+			if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+				return nil, domain.ErrLinkIsExists
+			}
+
+			return nil, err
+		}
+	}
+
+	variants := make([]string, 0)
+	variantArgs := make([]interface{}, 0)
+	tags := make([]string, 0)
+	tagArgs := make([]interface{}, 0)
+	for i, item := range items {
+		for _, v := range item.Link.Variants {
+			variants = append(variants, "(?, ?, ?)")
+			variantArgs = append(variantArgs, ids[i], v.Target, v.Weight)
+		}
+
+		for _, tagId := range item.TagIds {
+			tags = append(tags, "(?, ?)")
+			tagArgs = append(tagArgs, ids[i], tagId)
+		}
+	}
+
+	if len(variants) > 0 {
+		query := `INSERT INTO link_variants (link_id, target, weight) VALUES ` + strings.Join(variants, ", ")
+
+		_, err = tx.ExecContext(ctx, query, variantArgs...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(tags) > 0 {
+		query := `INSERT IGNORE INTO link_tag (link_id, tag_id) VALUES ` + strings.Join(tags, ", ")
+
+		_, err = tx.ExecContext(ctx, query, tagArgs...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, tx.Commit()
+}
+
+// insertedIds reads the ids of the links just inserted in tx by their domain and alias pairs,
+// keyArgs holds the pairs in the order of the inserted links
+func (m *mysqlLinkRepository) insertedIds(ctx context.Context, tx *sql.Tx, keys []string,
+	keyArgs []interface{}) ([]int64, error) {
+	query := `SELECT id, domain_id, alias FROM link WHERE (domain_id, alias) IN (` + strings.Join(keys, ", ") + `)`
+
+	rows, err := tx.QueryContext(ctx, query, keyArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]int64, len(keys))
+	for rows.Next() {
+		var (
+			id       int64
+			domainId int64
+			alias    string
+		)
+		if err = rows.Scan(&id, &domainId, &alias); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byKey[fmt.Sprintf("%d/%s", domainId, alias)] = id
+	}
+
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(keys))
+	for i := 0; i < len(keyArgs); i += 2 {
+		id, ok := byKey[fmt.Sprintf("%d/%s", keyArgs[i], keyArgs[i+1])]
+		if !ok {
+			return nil, fmt.Errorf("inserted link %v/%v is not found", keyArgs[i], keyArgs[i+1])
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
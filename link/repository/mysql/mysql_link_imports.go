@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type mysqlLinkImportsRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlLinkImportsRepository(conn *sql.DB) domain.LinkImportsRepository {
+	return &mysqlLinkImportsRepository{Conn: conn}
+}
+
+func (m *mysqlLinkImportsRepository) GetById(ctx context.Context, id int64) (domain.LinkImport, error) {
+	query := `SELECT id, status, dry_run, total, processed, created, failed, errors, created_at, finished_at
+				FROM link_imports WHERE id = ?`
+
+	var job domain.LinkImport
+	var errors []byte
+	err := m.Conn.QueryRowContext(ctx, query, id).Scan(
+		&job.ID,
+		&job.Status,
+		&job.DryRun,
+		&job.Total,
+		&job.Processed,
+		&job.Created,
+		&job.Failed,
+		&errors,
+		&job.CreatedAt,
+		&job.FinishedAt,
+	)
+	if err == sql.ErrNoRows {
+		return domain.LinkImport{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.LinkImport{}, err
+	}
+
+	job.Errors = make([]domain.LinkImportError, 0)
+	if len(errors) > 0 {
+		if err = json.Unmarshal(errors, &job.Errors); err != nil {
+			return domain.LinkImport{}, err
+		}
+	}
+
+	return job, nil
+}
+
+func (m *mysqlLinkImportsRepository) Store(ctx context.Context, job domain.LinkImport) (int64, error) {
+	query := `INSERT link_imports SET status = ?, dry_run = ?, total = ?, processed = 0, created = 0, failed = 0, errors = ?`
+
+	res, err := m.Conn.ExecContext(ctx, query, job.Status, job.DryRun, job.Total, "[]")
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// Update saves the progress of the import, the errors are kept as a JSON array
+func (m *mysqlLinkImportsRepository) Update(ctx context.Context, job domain.LinkImport) error {
+	errors, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE link_imports SET status = ?, processed = ?, created = ?, failed = ?, errors = ?, finished_at = ?
+				WHERE id = ?`
+
+	res, err := m.Conn.ExecContext(ctx, query, job.Status, job.Processed, job.Created, job.Failed, errors,
+		job.FinishedAt, job.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return fmt.Errorf("Total Affected: %d", rowsAffected)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+type mysqlLinkVariantsRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlLinkVariantsRepository(conn *sql.DB) domain.LinkVariantsRepository {
+	return &mysqlLinkVariantsRepository{Conn: conn}
+}
+
+func (m *mysqlLinkVariantsRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.LinkVariant, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.LinkVariant, 0)
+	for rows.Next() {
+		t := domain.LinkVariant{}
+		err = rows.Scan(
+			&t.ID,
+			&t.LinkId,
+			&t.Target,
+			&t.Weight,
+			&t.Visits,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlLinkVariantsRepository) FetchByLinkId(ctx context.Context, linkId int64) ([]domain.LinkVariant, error) {
+	query := `SELECT id, link_id, target, weight, 0, created_at, updated_at
+				FROM link_variants WHERE link_id = ? ORDER BY id`
+
+	return m.fetch(ctx, query, linkId)
+}
+
+// FetchWithVisitsByLinkId returns variants of the link with the number of visits of every variant
+func (m *mysqlLinkVariantsRepository) FetchWithVisitsByLinkId(ctx context.Context, linkId int64) ([]domain.LinkVariant, error) {
+	query := `SELECT lv.id, lv.link_id, lv.target, lv.weight, COUNT(v.id), lv.created_at, lv.updated_at
+				FROM link_variants as lv LEFT JOIN visits as v ON v.variant_id = lv.id
+				WHERE lv.link_id = ? GROUP BY lv.id ORDER BY lv.id`
+
+	return m.fetch(ctx, query, linkId)
+}
+
+// Replace removes variants of the link and stores the given ones in a single transaction
+func (m *mysqlLinkVariantsRepository) Replace(ctx context.Context, linkId int64, variants []domain.LinkVariant) (err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				logrus.Error(errRollback)
+			}
+		}
+	}()
+
+	if err = replaceVariants(ctx, tx, linkId, variants); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceVariants deletes the variants of the link and inserts the new ones in tx
+func replaceVariants(ctx context.Context, tx *sql.Tx, linkId int64, variants []domain.LinkVariant) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM link_variants WHERE link_id = ?`, linkId)
+	if err != nil {
+		return err
+	}
+
+	if len(variants) > 0 {
+		placeholders := make([]string, 0, len(variants))
+		args := make([]interface{}, 0, len(variants)*3)
+		for _, v := range variants {
+			placeholders = append(placeholders, "(?, ?, ?)")
+			args = append(args, linkId, v.Target, v.Weight)
+		}
+
+		query := `INSERT INTO link_variants (link_id, target, weight) VALUES ` + strings.Join(placeholders, ", ")
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type mysqlRedirectRulesRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlRedirectRulesRepository(conn *sql.DB) domain.RedirectRulesRepository {
+	return &mysqlRedirectRulesRepository{Conn: conn}
+}
+
+func (m *mysqlRedirectRulesRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.RedirectRule, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.RedirectRule, 0)
+	for rows.Next() {
+		t := domain.RedirectRule{}
+		err = rows.Scan(
+			&t.ID,
+			&t.LinkId,
+			&t.Position,
+			&t.Condition,
+			&t.Target,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlRedirectRulesRepository) FetchByLinkId(ctx context.Context, linkId int64) ([]domain.RedirectRule, error) {
+	query := `SELECT id, link_id, position, conditions, target, created_at, updated_at
+				FROM redirect_rules WHERE link_id = ? ORDER BY position, id`
+
+	return m.fetch(ctx, query, linkId)
+}
+
+func (m *mysqlRedirectRulesRepository) GetById(ctx context.Context, id int64) (domain.RedirectRule, error) {
+	query := `SELECT id, link_id, position, conditions, target, created_at, updated_at
+				FROM redirect_rules where id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+
+	if err != nil {
+		return domain.RedirectRule{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.RedirectRule{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlRedirectRulesRepository) Update(ctx context.Context, rule domain.RedirectRule) (int64, error) {
+	query := `UPDATE redirect_rules SET position = ?, conditions = ?, target = ?, updated_at = ? WHERE id = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, rule.Position, rule.Condition, rule.Target, rule.UpdatedAt, rule.ID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	affect, err := res.RowsAffected()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if affect != 1 {
+		return 0, fmt.Errorf("Total Affected: %d", affect)
+	}
+
+	return rule.ID, nil
+}
+
+func (m *mysqlRedirectRulesRepository) Store(ctx context.Context, rule domain.RedirectRule) (int64, error) {
+	query := `INSERT redirect_rules SET link_id = ?, position = ?, conditions = ?, target = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, rule.LinkId, rule.Position, rule.Condition, rule.Target)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected != 1 {
+		return 0, fmt.Errorf("Total affected: %d", rowsAffected)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *mysqlRedirectRulesRepository) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM redirect_rules WHERE id = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return fmt.Errorf("Total Affected: %d", rowsAffected)
+	}
+
+	return nil
+}
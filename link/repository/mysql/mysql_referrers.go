@@ -0,0 +1,162 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type mysqlReferrersRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlReferrersRepository(conn *sql.DB) domain.ReferrersRepository {
+	return &mysqlReferrersRepository{Conn: conn}
+}
+
+func (m *mysqlReferrersRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Referrers, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Referrers, 0)
+	for rows.Next() {
+		t := domain.Referrers{}
+		err = rows.Scan(
+			&t.ID,
+			&t.Hash,
+			&t.Url,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlReferrersRepository) GetById(ctx context.Context, id int64) (domain.Referrers, error) {
+	query := `SELECT id, hash, url, created_at, updated_at
+				FROM referrers where id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+
+	if err != nil {
+		return domain.Referrers{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.Referrers{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlReferrersRepository) GetByHash(ctx context.Context, hash string) (domain.Referrers, error) {
+	query := `SELECT id, hash, url, created_at, updated_at
+				FROM referrers where hash = ?`
+
+	list, err := m.fetch(ctx, query, hash)
+
+	if err != nil {
+		return domain.Referrers{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.Referrers{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlReferrersRepository) FirstOrCreate(ctx context.Context, referrer domain.Referrers) (int64, error) {
+	existed, err := m.GetByHash(ctx, referrer.Hash)
+	if err == nil {
+		return existed.ID, nil
+	}
+
+	query := `INSERT referrers SET hash = ?, url = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, referrer.Hash, referrer.Url)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 { // created by a concurrent request
+			existed, err = m.GetByHash(ctx, referrer.Hash)
+			if err != nil {
+				return 0, err
+			}
+
+			return existed.ID, nil
+		}
+
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected != 1 {
+		return 0, fmt.Errorf("Total affected: %d", rowsAffected)
+	}
+
+	return res.LastInsertId()
+}
+
+func (m *mysqlReferrersRepository) FetchTopByLinkId(ctx context.Context, linkId int64, limit int64) ([]domain.VisitsStat, error) {
+	query := `SELECT r.url, COUNT(v.id) as visits
+				FROM visits as v INNER JOIN referrers as r ON r.id = v.referrer_id
+				WHERE v.link_id = ? GROUP BY r.id, r.url ORDER BY visits DESC LIMIT ?`
+
+	rows, err := m.Conn.QueryContext(ctx, query, linkId, limit)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result := make([]domain.VisitsStat, 0)
+	for rows.Next() {
+		t := domain.VisitsStat{}
+		err = rows.Scan(
+			&t.Name,
+			&t.Visits,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
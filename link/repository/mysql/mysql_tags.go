@@ -7,6 +7,7 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"github.com/iambakhodir/short-link/domain"
 	"github.com/sirupsen/logrus"
+	"strings"
 )
 
 type mysqlTagsRepository struct {
@@ -106,6 +107,55 @@ func (m *mysqlTagsRepository) FetchByLinkId(ctx context.Context, linkId int64) (
 	return m.fetch(ctx, query, linkId)
 }
 
+func (m *mysqlTagsRepository) FetchByLinkIds(ctx context.Context, linkIds []int64) (map[int64][]domain.Tags, error) {
+	result := make(map[int64][]domain.Tags, len(linkIds))
+	if len(linkIds) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, 0, len(linkIds))
+	for _, id := range linkIds {
+		args = append(args, id)
+	}
+
+	query := `SELECT lt.link_id, t.id, t.name, t.created_at, t.updated_at
+				FROM tags as t JOIN link_tag as lt ON t.id = lt.tag_id
+				WHERE lt.link_id IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ") + `) ORDER BY t.id`
+
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	for rows.Next() {
+		var linkId int64
+		t := domain.Tags{}
+		err = rows.Scan(
+			&linkId,
+			&t.ID,
+			&t.Name,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result[linkId] = append(result[linkId], t)
+	}
+
+	return result, rows.Err()
+}
+
 func (m *mysqlTagsRepository) Update(ctx context.Context, tags domain.Tags) (int64, error) {
 	query := `UPDATE tags SET name = ?, updated_at = ? WHERE id = ?`
 
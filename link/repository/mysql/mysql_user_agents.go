@@ -0,0 +1,128 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+)
+
+type mysqlUserAgentsRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlUserAgentsRepository(conn *sql.DB) domain.UserAgentsRepository {
+	return &mysqlUserAgentsRepository{Conn: conn}
+}
+
+func (m *mysqlUserAgentsRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.UserAgents, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.UserAgents, 0)
+	for rows.Next() {
+		t := domain.UserAgents{}
+		err = rows.Scan(
+			&t.ID,
+			&t.BrowserId,
+			&t.DeviceId,
+			&t.Hash,
+			&t.Name,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlUserAgentsRepository) GetById(ctx context.Context, id int64) (domain.UserAgents, error) {
+	query := `SELECT id, browser_id, device_id, hash, name, created_at, updated_at
+				FROM user_agents where id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+
+	if err != nil {
+		return domain.UserAgents{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.UserAgents{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlUserAgentsRepository) GetByHash(ctx context.Context, hash string) (domain.UserAgents, error) {
+	query := `SELECT id, browser_id, device_id, hash, name, created_at, updated_at
+				FROM user_agents where hash = ?`
+
+	list, err := m.fetch(ctx, query, hash)
+
+	if err != nil {
+		return domain.UserAgents{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.UserAgents{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlUserAgentsRepository) FirstOrCreate(ctx context.Context, userAgent domain.UserAgents) (int64, error) {
+	existed, err := m.GetByHash(ctx, userAgent.Hash)
+	if err == nil {
+		return existed.ID, nil
+	}
+
+	query := `INSERT user_agents SET browser_id = ?, device_id = ?, hash = ?, name = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, userAgent.BrowserId, userAgent.DeviceId, userAgent.Hash, userAgent.Name)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 { // created by a concurrent request
+			existed, err = m.GetByHash(ctx, userAgent.Hash)
+			if err != nil {
+				return 0, err
+			}
+
+			return existed.ID, nil
+		}
+
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected != 1 {
+		return 0, fmt.Errorf("Total affected: %d", rowsAffected)
+	}
+
+	return res.LastInsertId()
+}
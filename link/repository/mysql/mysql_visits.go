@@ -0,0 +1,281 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+type mysqlVisitsRepository struct {
+	Conn *sql.DB
+}
+
+func NewMysqlVisitsRepository(conn *sql.DB) domain.VisitsRepository {
+	return &mysqlVisitsRepository{Conn: conn}
+}
+
+func (m *mysqlVisitsRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Visits, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Visits, 0)
+	for rows.Next() {
+		t := domain.Visits{}
+		err = rows.Scan(
+			&t.ID,
+			&t.LinkId,
+			&t.UserAgentId,
+			&t.ReferrerId,
+			&t.Ip,
+			&t.Headers,
+			&t.QueryString,
+			&t.VariantId,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlVisitsRepository) Fetch(ctx context.Context, limit int64) ([]domain.Visits, error) {
+	query := `SELECT id, link_id, user_agent_id, referrer_id, ip, headers, query_string, variant_id, created_at, updated_at
+				FROM visits ORDER BY created_at DESC LIMIT ?`
+
+	res, err := m.fetch(ctx, query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *mysqlVisitsRepository) GetById(ctx context.Context, id int64) (domain.Visits, error) {
+	query := `SELECT id, link_id, user_agent_id, referrer_id, ip, headers, query_string, variant_id, created_at, updated_at
+				FROM visits where id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+
+	if err != nil {
+		return domain.Visits{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.Visits{}, domain.ErrNotFound
+	}
+}
+
+// GetByAlias returns the latest visit of the link with the given alias
+func (m *mysqlVisitsRepository) GetByAlias(ctx context.Context, alias string) (domain.Visits, error) {
+	query := `SELECT v.id, v.link_id, v.user_agent_id, v.referrer_id, v.ip, v.headers, v.query_string, v.variant_id,
+				v.created_at, v.updated_at
+				FROM visits as v INNER JOIN link as l
+				    ON l.id = v.link_id where l.alias = ? ORDER BY v.id DESC LIMIT 1`
+
+	list, err := m.fetch(ctx, query, alias)
+
+	if err != nil {
+		return domain.Visits{}, err
+	}
+
+	if len(list) > 0 {
+		return list[0], nil
+	} else {
+		return domain.Visits{}, domain.ErrNotFound
+	}
+}
+
+func (m *mysqlVisitsRepository) Update(ctx context.Context, visit domain.Visits) (int64, error) {
+	query := `UPDATE visits SET link_id = ?, user_agent_id = ?, referrer_id = ?, ip = ?, headers = ?, query_string = ?, variant_id = ?,
+				updated_at = ? WHERE id = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, visit.LinkId, visit.UserAgentId, visit.ReferrerId, visit.Ip, visit.Headers,
+		visit.QueryString, visit.VariantId, visit.UpdatedAt, visit.ID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	affect, err := res.RowsAffected()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if affect != 1 {
+		return 0, fmt.Errorf("Total Affected: %d", affect)
+	}
+
+	return visit.ID, nil
+}
+
+func (m *mysqlVisitsRepository) Store(ctx context.Context, visit domain.Visits) (int64, error) {
+	query := `INSERT visits SET link_id = ?, user_agent_id = ?, referrer_id = ?, ip = ?, headers = ?, query_string = ?,
+				variant_id = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, visit.LinkId, visit.UserAgentId, visit.ReferrerId, visit.Ip, visit.Headers,
+		visit.QueryString, visit.VariantId)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected != 1 {
+		return 0, fmt.Errorf("Total affected: %d", rowsAffected)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *mysqlVisitsRepository) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM visits WHERE id = ?`
+
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return fmt.Errorf("Total Affected: %d", rowsAffected)
+	}
+
+	return nil
+}
+
+// StoreBatch inserts all given visits with a single multi-row INSERT
+func (m *mysqlVisitsRepository) StoreBatch(ctx context.Context, visits []domain.Visits) error {
+	if len(visits) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(visits))
+	args := make([]interface{}, 0, len(visits)*7)
+	for _, visit := range visits {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, visit.LinkId, visit.UserAgentId, visit.ReferrerId, visit.Ip, visit.Headers, visit.QueryString,
+			visit.VariantId)
+	}
+
+	query := `INSERT INTO visits (link_id, user_agent_id, referrer_id, ip, headers, query_string, variant_id) VALUES ` +
+		strings.Join(placeholders, ", ")
+
+	res, err := m.Conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != int64(len(visits)) {
+		return fmt.Errorf("Total affected: %d", rowsAffected)
+	}
+
+	return nil
+}
+
+func (m *mysqlVisitsRepository) fetchStat(ctx context.Context, query string, args ...interface{}) (result []domain.VisitsStat, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.VisitsStat, 0)
+	for rows.Next() {
+		t := domain.VisitsStat{}
+		err = rows.Scan(
+			&t.Name,
+			&t.Visits,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlVisitsRepository) FetchBrowsersStat(ctx context.Context, linkId int64, limit int64) ([]domain.VisitsStat, error) {
+	query := `SELECT CONCAT_WS(' ', b.name, NULLIF(b.version, '')) as browser, COUNT(v.id) as visits
+				FROM visits as v
+				    INNER JOIN user_agents as ua ON ua.id = v.user_agent_id
+				    INNER JOIN browsers as b ON b.id = ua.browser_id
+				WHERE v.link_id = ? GROUP BY browser ORDER BY visits DESC LIMIT ?`
+
+	return m.fetchStat(ctx, query, linkId, limit)
+}
+
+func (m *mysqlVisitsRepository) FetchDevicesStat(ctx context.Context, linkId int64, limit int64) ([]domain.VisitsStat, error) {
+	query := `SELECT d.name as device, COUNT(v.id) as visits
+				FROM visits as v
+				    INNER JOIN user_agents as ua ON ua.id = v.user_agent_id
+				    INNER JOIN devices as d ON d.id = ua.device_id
+				WHERE v.link_id = ? GROUP BY device ORDER BY visits DESC LIMIT ?`
+
+	return m.fetchStat(ctx, query, linkId, limit)
+}
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/random"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// AliasKeyPoolConfig represent the settings of the pre-generated alias pool
+type AliasKeyPoolConfig struct {
+	// Length of the pooled aliases, other lengths are generated by the fallback
+	Length int
+	// ClaimSize is how many aliases an instance claims at once
+	ClaimSize int
+	// LowWater is the number of unclaimed aliases below which the refiller adds RefillSize new ones
+	LowWater       int64
+	RefillSize     int
+	RefillInterval time.Duration
+}
+
+type aliasKeyPool struct {
+	keysRepo       domain.AliasKeysRepository
+	generator      random.AliasGenerator
+	fallback       random.AliasGenerator
+	contextTimeout time.Duration
+	config         AliasKeyPoolConfig
+
+	mu   sync.Mutex
+	keys []string
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewAliasKeyPool starts the background refiller of the alias pool. New aliases are made by generator,
+// aliases of another length, or any when the pool can't be claimed from, come from fallback.
+// Claimed aliases which are not handed out before the instance stops are lost.
+func NewAliasKeyPool(keysRepo domain.AliasKeysRepository, generator random.AliasGenerator, fallback random.AliasGenerator,
+	config AliasKeyPoolConfig, timeout time.Duration) domain.AliasKeyPool {
+	if config.Length <= 0 {
+		config.Length = 7
+	}
+	if config.ClaimSize <= 0 {
+		config.ClaimSize = 100
+	}
+	if config.LowWater <= 0 {
+		config.LowWater = 10000
+	}
+	if config.RefillSize <= 0 {
+		config.RefillSize = 5000
+	}
+	if config.RefillInterval <= 0 {
+		config.RefillInterval = 10 * time.Second
+	}
+
+	p := &aliasKeyPool{
+		keysRepo:       keysRepo,
+		generator:      generator,
+		fallback:       fallback,
+		contextTimeout: timeout,
+		config:         config,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	go p.refill()
+
+	return p
+}
+
+// Generate hands out a pooled alias without checking it for collisions. The pool is claimed from
+// without holding the lock, aliases claimed by concurrent callers all end up in the pool.
+func (p *aliasKeyPool) Generate(length int) (string, error) {
+	if length != p.config.Length {
+		return p.fallback.Generate(length)
+	}
+
+	if alias, ok := p.pop(); ok {
+		return alias, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.contextTimeout)
+	keys, err := p.keysRepo.Claim(ctx, p.config.ClaimSize)
+	cancel()
+
+	if err != nil {
+		logrus.Error(err)
+	}
+	if len(keys) == 0 {
+		return p.fallback.Generate(length)
+	}
+
+	p.mu.Lock()
+	p.keys = append(p.keys, keys...)
+	p.mu.Unlock()
+
+	if alias, ok := p.pop(); ok {
+		return alias, nil
+	}
+
+	return p.fallback.Generate(length)
+}
+
+func (p *aliasKeyPool) pop() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.keys) == 0 {
+		return "", false
+	}
+
+	alias := p.keys[len(p.keys)-1]
+	p.keys = p.keys[:len(p.keys)-1]
+
+	return alias, true
+}
+
+func (p *aliasKeyPool) Close(ctx context.Context) error {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *aliasKeyPool) refill() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.RefillInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.refillOnce(); err != nil {
+			logrus.Error(err)
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refillOnce adds RefillSize aliases when the pool is below the low-water mark,
+// every instance may refill as aliases which are already in the pool are skipped
+func (p *aliasKeyPool) refillOnce() error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.contextTimeout)
+	defer cancel()
+
+	count, err := p.keysRepo.CountUnclaimed(ctx)
+	if err != nil || count >= p.config.LowWater {
+		return err
+	}
+
+	aliases := make([]string, 0, p.config.RefillSize)
+	for len(aliases) < p.config.RefillSize {
+		alias, err := p.generator.Generate(p.config.Length)
+		if err != nil {
+			return err
+		}
+		aliases = append(aliases, alias)
+	}
+
+	// insert in chunks, so a single statement stays well below max_allowed_packet
+	for start := 0; start < len(aliases); start += 1000 {
+		end := start + 1000
+		if end > len(aliases) {
+			end = len(aliases)
+		}
+
+		if _, err = p.keysRepo.Insert(ctx, aliases[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"github.com/iambakhodir/short-link/domain"
+	"net"
+	"strings"
+	"time"
+)
+
+type domainsUseCase struct {
+	domainsRepo    domain.DomainsRepository
+	contextTimeout time.Duration
+}
+
+func NewDomainsUseCase(domainsRepo domain.DomainsRepository, timeout time.Duration) domain.DomainsUseCase {
+	return &domainsUseCase{domainsRepo: domainsRepo, contextTimeout: timeout}
+}
+
+func (du domainsUseCase) Fetch(ctx context.Context, limit int64) ([]domain.Domain, error) {
+	if limit == 0 {
+		limit = 10
+	} else if limit > 100 {
+		limit = 100
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, du.contextTimeout)
+	defer cancel()
+
+	return du.domainsRepo.Fetch(ctx, limit)
+}
+
+func (du domainsUseCase) GetById(ctx context.Context, id int64) (domain.Domain, error) {
+	ctx, cancel := context.WithTimeout(ctx, du.contextTimeout)
+	defer cancel()
+
+	return du.domainsRepo.GetById(ctx, id)
+}
+
+func (du domainsUseCase) GetByHost(ctx context.Context, host string) (domain.Domain, error) {
+	ctx, cancel := context.WithTimeout(ctx, du.contextTimeout)
+	defer cancel()
+
+	return du.domainsRepo.GetByHost(ctx, normalizeHost(host))
+}
+
+func (du domainsUseCase) Update(ctx context.Context, d domain.Domain) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, du.contextTimeout)
+	defer cancel()
+
+	_, err := du.domainsRepo.GetById(ctx, d.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	d.Host = normalizeHost(d.Host)
+	d.UpdatedAt = time.Now()
+
+	return du.domainsRepo.Update(ctx, d)
+}
+
+func (du domainsUseCase) Store(ctx context.Context, d domain.Domain) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, du.contextTimeout)
+	defer cancel()
+
+	d.Host = normalizeHost(d.Host)
+
+	return du.domainsRepo.Store(ctx, d)
+}
+
+func (du domainsUseCase) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, du.contextTimeout)
+	defer cancel()
+
+	_, err := du.domainsRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return du.domainsRepo.Delete(ctx, id)
+}
+
+func (du domainsUseCase) Resolve(ctx context.Context, host string) (domain.Domain, error) {
+	res, err := du.GetByHost(ctx, host)
+	if err == domain.ErrNotFound {
+		return domain.Domain{}, nil
+	}
+
+	return res, err
+}
+
+// normalizeHost drops the port and the trailing dot of the host and lowercases it
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/random"
+	"time"
+)
+
+type idempotencyUseCase struct {
+	keysRepo       domain.IdempotencyKeysRepository
+	ttl            time.Duration
+	lease          time.Duration
+	contextTimeout time.Duration
+}
+
+// NewIdempotencyUseCase keeps the responses of idempotent requests for ttl, 24 hours by default.
+// A running request holds its key for lease, a minute by default, a retry after it takes the key over.
+func NewIdempotencyUseCase(keysRepo domain.IdempotencyKeysRepository, ttl time.Duration, lease time.Duration,
+	timeout time.Duration) domain.IdempotencyUseCase {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if lease <= 0 {
+		lease = time.Minute
+	}
+
+	return &idempotencyUseCase{keysRepo: keysRepo, ttl: ttl, lease: lease, contextTimeout: timeout}
+}
+
+func (iu idempotencyUseCase) Begin(ctx context.Context, key string, fingerprint string) (domain.IdempotencyKey, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, iu.contextTimeout)
+	defer cancel()
+
+	reservation := domain.IdempotencyKey{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Owner:       random.NewRandomString(32),
+		ExpiresAt:   time.Now().Add(iu.lease),
+	}
+
+	err := iu.keysRepo.Reserve(ctx, reservation)
+	if err == nil {
+		return reservation, false, nil
+	}
+	if err != domain.ErrConflict {
+		return domain.IdempotencyKey{}, false, err
+	}
+
+	existed, err := iu.keysRepo.GetByKey(ctx, key)
+	if err == domain.ErrNotFound {
+		// the key expired right between the calls
+		return domain.IdempotencyKey{}, false, domain.ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return domain.IdempotencyKey{}, false, err
+	}
+
+	if existed.Fingerprint != fingerprint {
+		return domain.IdempotencyKey{}, false, domain.ErrIdempotencyKeyReused
+	}
+
+	if existed.StatusCode == 0 {
+		return domain.IdempotencyKey{}, false, domain.ErrIdempotencyInProgress
+	}
+
+	return existed, true, nil
+}
+
+func (iu idempotencyUseCase) Complete(ctx context.Context, reservation domain.IdempotencyKey, statusCode int,
+	response []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, iu.contextTimeout)
+	defer cancel()
+
+	return iu.keysRepo.Complete(ctx, reservation.Key, reservation.Owner, statusCode, response, time.Now().Add(iu.ttl))
+}
+
+func (iu idempotencyUseCase) Release(ctx context.Context, reservation domain.IdempotencyKey) error {
+	ctx, cancel := context.WithTimeout(ctx, iu.contextTimeout)
+	defer cancel()
+
+	return iu.keysRepo.Delete(ctx, reservation.Key, reservation.Owner)
+}
+
+// Purge deletes the expired keys
+func (iu idempotencyUseCase) Purge(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, iu.contextTimeout)
+	defer cancel()
+
+	return iu.keysRepo.DeleteExpired(ctx, time.Now())
+}
@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// LinkImporterConfig represent the settings of the background link imports
+type LinkImporterConfig struct {
+	// QueueSize is how many imports may wait for a worker
+	QueueSize int
+	Workers   int
+	// BatchSize is how many rows are stored at once, the progress is saved after every batch
+	BatchSize int
+}
+
+type linkImportJob struct {
+	job  domain.LinkImport
+	rows []domain.LinkImportRow
+}
+
+type linkImporter struct {
+	importsRepo    domain.LinkImportsRepository
+	linkUcase      domain.LinkUseCase
+	tagsUcase      domain.TagsUseCase
+	contextTimeout time.Duration
+	config         LinkImporterConfig
+
+	queue  chan linkImportJob
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewLinkImporter starts the workers of the link imports. The rows of a queued import are kept in memory,
+// an import which was running when its instance stopped stays in the running status.
+func NewLinkImporter(importsRepo domain.LinkImportsRepository, linkUcase domain.LinkUseCase, tagsUcase domain.TagsUseCase,
+	config LinkImporterConfig, timeout time.Duration) domain.LinkImporter {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 10
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+
+	li := &linkImporter{
+		importsRepo:    importsRepo,
+		linkUcase:      linkUcase,
+		tagsUcase:      tagsUcase,
+		contextTimeout: timeout,
+		config:         config,
+		queue:          make(chan linkImportJob, config.QueueSize),
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		li.wg.Add(1)
+		go li.work()
+	}
+
+	return li
+}
+
+func (li *linkImporter) Start(ctx context.Context, rows []domain.LinkImportRow, dryRun bool) (domain.LinkImport, error) {
+	li.mu.RLock()
+	defer li.mu.RUnlock()
+
+	if li.closed {
+		return domain.LinkImport{}, domain.ErrWriterClosed
+	}
+
+	// the job is only stored when it fits in the queue
+	if len(li.queue) == cap(li.queue) {
+		return domain.LinkImport{}, domain.ErrQueueFull
+	}
+
+	job := domain.LinkImport{
+		Status:    domain.LinkImportPending,
+		DryRun:    dryRun,
+		Total:     len(rows),
+		Errors:    make([]domain.LinkImportError, 0),
+		CreatedAt: time.Now(),
+	}
+
+	storeCtx, cancel := context.WithTimeout(ctx, li.contextTimeout)
+	defer cancel()
+
+	id, err := li.importsRepo.Store(storeCtx, job)
+	if err != nil {
+		return domain.LinkImport{}, err
+	}
+	job.ID = id
+
+	select {
+	case li.queue <- linkImportJob{job: job, rows: rows}:
+		return job, nil
+	default:
+		li.finish(job, domain.LinkImportFailed)
+		return domain.LinkImport{}, domain.ErrQueueFull
+	}
+}
+
+func (li *linkImporter) GetById(ctx context.Context, id int64) (domain.LinkImport, error) {
+	ctx, cancel := context.WithTimeout(ctx, li.contextTimeout)
+	defer cancel()
+
+	return li.importsRepo.GetById(ctx, id)
+}
+
+func (li *linkImporter) Close(ctx context.Context) error {
+	li.mu.Lock()
+	if !li.closed {
+		li.closed = true
+		close(li.queue)
+	}
+	li.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		li.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (li *linkImporter) work() {
+	defer li.wg.Done()
+
+	for j := range li.queue {
+		li.run(j.job, j.rows)
+	}
+}
+
+// run stores or, in a dry run, checks the rows batch by batch and saves the progress after every batch
+func (li *linkImporter) run(job domain.LinkImport, rows []domain.LinkImportRow) {
+	job.Status = domain.LinkImportRunning
+	li.save(job)
+
+	ctx := context.Background()
+	tagIds := make(map[string]int64)
+
+	for start := 0; start < len(rows); start += li.config.BatchSize {
+		end := start + li.config.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		valid := make([]domain.LinkImportRow, 0, end-start)
+		items := make([]domain.LinkBatchItem, 0, end-start)
+		for _, row := range rows[start:end] {
+			if row.Err == nil && !job.DryRun {
+				row.Item.TagIds, row.Err = li.tagIds(ctx, row.Tags, tagIds)
+			}
+
+			if row.Err != nil {
+				job.Failed++
+				job.Errors = append(job.Errors, domain.LinkImportError{Row: row.Row, Error: row.Err.Error()})
+				continue
+			}
+
+			valid = append(valid, row)
+			items = append(items, row.Item)
+		}
+
+		var results []domain.LinkBatchResult
+		var err error
+		if len(items) > 0 {
+			if job.DryRun {
+				results, err = li.linkUcase.CheckBatch(ctx, items)
+			} else {
+				results, err = li.linkUcase.StoreBatch(ctx, items, false)
+			}
+		}
+		if err != nil {
+			logrus.Error(err)
+			job.Processed = start
+			li.finish(job, domain.LinkImportFailed)
+			return
+		}
+
+		for n, res := range results {
+			if res.Err != nil {
+				job.Failed++
+				job.Errors = append(job.Errors, domain.LinkImportError{Row: valid[n].Row, Error: res.Err.Error()})
+				continue
+			}
+
+			job.Created++
+		}
+
+		job.Processed = end
+		li.save(job)
+	}
+
+	li.finish(job, domain.LinkImportFinished)
+}
+
+// tagIds returns the ids of the tags, creating the missing ones, ids are cached for the whole import
+func (li *linkImporter) tagIds(ctx context.Context, names []string, cache map[string]int64) ([]int64, error) {
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		id, ok := cache[name]
+		if !ok {
+			var err error
+			id, err = li.tagsUcase.FirstOrCreate(ctx, domain.Tags{Name: name})
+			if err != nil {
+				return nil, err
+			}
+			cache[name] = id
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (li *linkImporter) finish(job domain.LinkImport, status string) {
+	job.Status = status
+	job.FinishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	li.save(job)
+}
+
+func (li *linkImporter) save(job domain.LinkImport) {
+	ctx, cancel := context.WithTimeout(context.Background(), li.contextTimeout)
+	defer cancel()
+
+	if err := li.importsRepo.Update(ctx, job); err != nil {
+		logrus.Error(err)
+	}
+}
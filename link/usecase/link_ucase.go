@@ -2,30 +2,51 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
 	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/query"
+	"github.com/iambakhodir/short-link/domain/random"
+	"golang.org/x/crypto/bcrypt"
 	"time"
 )
 
+const (
+	// aliasAttempts is how many generated aliases are tried before StoreGenerated gives up
+	aliasAttempts = 5
+	// aliasBackoff is the pause after the first taken alias, it doubles with every attempt
+	aliasBackoff = 10 * time.Millisecond
+	// batchSize is how many links StoreBatch inserts with a single statement when it isn't atomic
+	batchSize = 100
+)
+
 type linkUseCase struct {
 	linkRepo       domain.LinkRepository
+	aliasGenerator random.AliasGenerator
+	idAliases      random.IdAliasGenerator
 	contextTimeout time.Duration
 }
 
-func NewLinkUseCase(linkRepo domain.LinkRepository, timeout time.Duration) domain.LinkUseCase {
-	return &linkUseCase{linkRepo: linkRepo, contextTimeout: timeout}
+// NewLinkUseCase returns the link's use-cases. Aliases are derived from link ids when idAliases is set,
+// otherwise they are generated by aliasGenerator.
+func NewLinkUseCase(linkRepo domain.LinkRepository, aliasGenerator random.AliasGenerator, idAliases random.IdAliasGenerator,
+	timeout time.Duration) domain.LinkUseCase {
+	return &linkUseCase{linkRepo: linkRepo, aliasGenerator: aliasGenerator, idAliases: idAliases, contextTimeout: timeout}
 }
 
-func (lu linkUseCase) Fetch(ctx context.Context, limit int64) ([]domain.Link, error) {
-	if limit == 0 {
-		limit = 10
-	} else if limit > 100 {
-		limit = 100
+func (lu linkUseCase) Fetch(ctx context.Context, filter domain.LinkFilter) ([]domain.Link, error) {
+	if filter.Limit == 0 {
+		filter.Limit = 10
+	} else if filter.Limit > 100 {
+		filter.Limit = 100
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
 	defer cancel()
 
-	res, err := lu.linkRepo.Fetch(ctx, limit)
+	res, err := lu.linkRepo.Fetch(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +64,10 @@ func (lu linkUseCase) GetById(ctx context.Context, id int64) (domain.Link, error
 		return domain.Link{}, err
 	}
 
+	if res.DeletedAt.Valid {
+		return domain.Link{}, domain.ErrLinkDeleted
+	}
+
 	return res, nil
 }
 
@@ -51,29 +76,185 @@ func (lu linkUseCase) Update(ctx context.Context, link domain.Link) (int64, erro
 	defer cancel()
 
 	link.UpdatedAt = time.Now()
+	link.TargetHash = targetHash(link.Target)
 
 	return lu.linkRepo.Update(ctx, link)
 }
 
-func (lu linkUseCase) GetByAlias(ctx context.Context, alias string) (domain.Link, error) {
+// UpdateIfUnmodified validates the changed link and hashes its new password before it is stored
+func (lu linkUseCase) UpdateIfUnmodified(ctx context.Context, update domain.LinkUpdate,
+	unmodifiedSince time.Time) (domain.Link, error) {
+	link, err := lu.prepare(update.Link)
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	// updated_at is the version of the link, it has to move forward even within the second MySQL keeps
+	link.UpdatedAt = time.Now().Truncate(time.Second)
+	if !link.UpdatedAt.After(unmodifiedSince) {
+		link.UpdatedAt = unmodifiedSince.Truncate(time.Second).Add(time.Second)
+	}
+
+	update.Link = link
+	if err = lu.linkRepo.UpdateIfUnmodified(ctx, update, unmodifiedSince); err != nil {
+		return domain.Link{}, err
+	}
+
+	return link, nil
+}
+
+func (lu linkUseCase) GetByAlias(ctx context.Context, domainId int64, alias string) (domain.Link, error) {
 	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
 	defer cancel()
 
-	res, err := lu.linkRepo.GetByAlias(ctx, alias)
+	res, err := lu.getByIdAlias(ctx, domainId, alias)
+	if err == domain.ErrNotFound {
+		res, err = lu.linkRepo.GetByAlias(ctx, domainId, alias)
+	}
 	if err != nil {
 		return domain.Link{}, err
 	}
 
+	if res.DeletedAt.Valid {
+		return domain.Link{}, domain.ErrLinkDeleted
+	}
+
 	return res, nil
 }
 
 func (lu linkUseCase) Store(ctx context.Context, link domain.Link) (int64, error) {
+	link, err := lu.prepare(link)
+	if err != nil {
+		return 0, err
+	}
+
+	return lu.storeWithTimeout(ctx, link)
+}
+
+// getByIdAlias looks the alias up by the primary key when it is derived from an id
+func (lu linkUseCase) getByIdAlias(ctx context.Context, domainId int64, alias string) (domain.Link, error) {
+	if lu.idAliases == nil {
+		return domain.Link{}, domain.ErrNotFound
+	}
+
+	id, ok := lu.idAliases.IdOf(alias)
+	if !ok {
+		return domain.Link{}, domain.ErrNotFound
+	}
+
+	res, err := lu.linkRepo.GetById(ctx, id)
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	// a custom alias can look like a derived one
+	if res.Alias != alias || res.DomainId != domainId {
+		return domain.Link{}, domain.ErrNotFound
+	}
+
+	return res, nil
+}
+
+// StoreGenerated stores the link under a generated alias of the given length, or under the alias
+// derived from its id, which ignores the length. When the generated alias is taken a new one
+// is generated after a pause which doubles with every attempt.
+func (lu linkUseCase) StoreGenerated(ctx context.Context, link domain.Link, length int) (int64, error) {
+	link, err := lu.prepare(link)
+	if err != nil {
+		return 0, err
+	}
+
+	if lu.idAliases != nil {
+		return lu.storeWithIdAlias(ctx, link)
+	}
+
+	backoff := aliasBackoff
+	for attempt := 1; ; attempt++ {
+		link.Alias, err = lu.aliasGenerator.Generate(length)
+		if err != nil {
+			return 0, err
+		}
+
+		id, err := lu.storeWithTimeout(ctx, link)
+		if err != domain.ErrLinkIsExists {
+			return id, err
+		}
+
+		if observer, ok := lu.aliasGenerator.(random.CollisionObserver); ok {
+			observer.Collided()
+		}
+
+		if attempt == aliasAttempts {
+			return 0, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// storeWithIdAlias stores the link under the alias of its id. An alias which is skipped or taken
+// by a custom one burns the id, the next attempt gets another id.
+func (lu linkUseCase) storeWithIdAlias(ctx context.Context, link domain.Link) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		id, err := lu.linkRepo.StoreWithIdAlias(ctx, link, lu.idAliases.AliasOf)
+		if (err != domain.ErrLinkIsExists && err != random.ErrSkipped) || attempt == aliasAttempts {
+			return id, err
+		}
+	}
+}
+
+func (lu linkUseCase) storeWithTimeout(ctx context.Context, link domain.Link) (int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
 	defer cancel()
 
 	return lu.linkRepo.Store(ctx, link)
 }
 
+// validate checks the fields of the new link which depend on each other
+func validate(link domain.Link) error {
+	if link.ActivateAt.Valid && link.DeactivateAt.Valid && !link.DeactivateAt.Time.After(link.ActivateAt.Time) {
+		return domain.ErrBadParamInput
+	}
+
+	return nil
+}
+
+// prepare validates the new link and hashes its password
+func (lu linkUseCase) prepare(link domain.Link) (domain.Link, error) {
+	if err := validate(link); err != nil {
+		return domain.Link{}, err
+	}
+
+	if link.Status == "" {
+		link.Status = domain.LinkStatusActive
+	}
+
+	link.TargetHash = targetHash(link.Target)
+
+	if link.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(link.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return domain.Link{}, err
+		}
+
+		link.PasswordHash = sql.NullString{String: string(hash), Valid: true}
+		link.Password = ""
+	}
+
+	return link, nil
+}
+
 func (lu linkUseCase) Delete(ctx context.Context, id int64) error {
 	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
 	defer cancel()
@@ -83,9 +264,363 @@ func (lu linkUseCase) Delete(ctx context.Context, id int64) error {
 		return err
 	}
 
-	if existedLink == (domain.Link{}) {
+	if existedLink.ID == 0 {
 		return domain.ErrNotFound
 	}
 
+	if existedLink.DeletedAt.Valid {
+		return domain.ErrLinkDeleted
+	}
+
 	return lu.linkRepo.Delete(ctx, id)
 }
+
+// Hit checks that the link is live and counts the visit when the link has a visits limit
+func (lu linkUseCase) Hit(ctx context.Context, link domain.Link) error {
+	if link.Status == domain.LinkStatusDisabled {
+		return domain.ErrLinkDisabled
+	}
+
+	switch link.State(time.Now()) {
+	case domain.LinkStateScheduled:
+		return domain.ErrLinkNotActive
+	case domain.LinkStateEnded:
+		return domain.ErrLinkExpired
+	}
+
+	if !link.MaxVisits.Valid {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	return lu.linkRepo.IncrementVisits(ctx, link.ID)
+}
+
+// CheckPassword compares the password with the link's password hash
+func (lu linkUseCase) CheckPassword(link domain.Link, password string) error {
+	if !link.PasswordHash.Valid {
+		return nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash.String), []byte(password))
+	if err != nil {
+		return domain.ErrInvalidPassword
+	}
+
+	return nil
+}
+
+// Restore takes the deleted link out of the trash
+func (lu linkUseCase) Restore(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	return lu.linkRepo.Restore(ctx, id)
+}
+
+// Purge hard deletes the links which stayed in the trash longer than the retention period
+func (lu linkUseCase) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	return lu.linkRepo.Purge(ctx, time.Now().Add(-retention))
+}
+
+// Enable resumes the disabled link
+func (lu linkUseCase) Enable(ctx context.Context, id int64) error {
+	return lu.transition(ctx, id, domain.LinkStatusDisabled, domain.LinkStatusActive)
+}
+
+// Disable pauses the active link, its alias stays reserved and its visits are kept
+func (lu linkUseCase) Disable(ctx context.Context, id int64) error {
+	return lu.transition(ctx, id, domain.LinkStatusActive, domain.LinkStatusDisabled)
+}
+
+// transition moves the link between the stored statuses. Deleted links can't be changed
+// and a link which is not in the from status is a conflict.
+func (lu linkUseCase) transition(ctx context.Context, id int64, from string, to string) error {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	link, err := lu.linkRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if link.DeletedAt.Valid {
+		return domain.ErrLinkDeleted
+	}
+
+	if link.Status != from {
+		return domain.ErrConflict
+	}
+
+	return lu.linkRepo.UpdateStatus(ctx, id, from, to)
+}
+
+func (lu linkUseCase) GetByTarget(ctx context.Context, userId int64, domainId int64, target string) (domain.Link, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	res, err := lu.linkRepo.GetByTargetHash(ctx, userId, domainId, targetHash(target))
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	// an ended link can't be handed out again
+	if res.LifecycleStatus(time.Now()) != domain.LinkStatusActive {
+		return domain.Link{}, domain.ErrNotFound
+	}
+
+	return res, nil
+}
+
+// StoreBatch prepares the links and generates their aliases up front, so the links are inserted in batches.
+// Without the atomic mode every batch is stored on its own and a failed batch is stored link by link,
+// so only the failing links are reported.
+func (lu linkUseCase) StoreBatch(ctx context.Context, items []domain.LinkBatchItem, atomic bool) ([]domain.LinkBatchResult, error) {
+	results := make([]domain.LinkBatchResult, len(items))
+	items = append([]domain.LinkBatchItem(nil), items...)
+
+	for i := range items {
+		items[i].Link, results[i].Err = lu.prepare(items[i].Link)
+	}
+
+	if err := lu.assignAliases(ctx, items, results); err != nil {
+		return nil, err
+	}
+
+	pending := make([]int, 0, len(items))
+	for i := range items {
+		if results[i].Err == nil {
+			pending = append(pending, i)
+		}
+	}
+
+	if atomic {
+		if len(pending) != len(items) {
+			abortBatch(results)
+			return results, nil
+		}
+
+		// the link which broke the batch is unknown, its error is reported for every link
+		if err := lu.storeBatch(ctx, items, pending, results); err != nil {
+			for i := range results {
+				results[i].Err = err
+			}
+		}
+
+		return results, nil
+	}
+
+	for start := 0; start < len(pending); start += batchSize {
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		if err := lu.storeBatch(ctx, items, pending[start:end], results); err == nil {
+			continue
+		}
+
+		for _, i := range pending[start:end] {
+			results[i].Err = lu.storeBatch(ctx, items, []int{i}, results)
+		}
+	}
+
+	return results, nil
+}
+
+// assignAliases generates the missing aliases and fails the links whose custom alias is taken, in the database
+// or by an earlier link of the batch. Taken generated aliases are generated again.
+func (lu linkUseCase) assignAliases(ctx context.Context, items []domain.LinkBatchItem, results []domain.LinkBatchResult) error {
+	custom := make([]bool, len(items))
+	check := make([]int, 0, len(items))
+	for i := range items {
+		custom[i] = items[i].Link.Alias != ""
+		// the sequential strategy derives the alias from the id when the link is inserted
+		if results[i].Err == nil && (custom[i] || lu.idAliases == nil) {
+			check = append(check, i)
+		}
+	}
+
+	used := make(map[string]bool, len(check))
+	for attempt := 1; len(check) > 0; attempt++ {
+		links := make([]domain.Link, 0, len(check))
+		for _, i := range check {
+			if items[i].Link.Alias == "" {
+				alias, err := lu.aliasGenerator.Generate(items[i].Length)
+				if err != nil {
+					return err
+				}
+				items[i].Link.Alias = alias
+			}
+
+			links = append(links, items[i].Link)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+		taken, err := lu.linkRepo.TakenAliases(ctx, links)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		retry := make([]int, 0)
+		for n, i := range check {
+			key := fmt.Sprintf("%d/%s", items[i].Link.DomainId, items[i].Link.Alias)
+			if !taken[n] && !used[key] {
+				used[key] = true
+				continue
+			}
+
+			if custom[i] {
+				results[i].Err = domain.ErrLinkIsExists
+				continue
+			}
+
+			if observer, ok := lu.aliasGenerator.(random.CollisionObserver); ok {
+				observer.Collided()
+			}
+
+			items[i].Link.Alias = ""
+			if attempt == aliasAttempts {
+				results[i].Err = domain.ErrLinkIsExists
+				continue
+			}
+			retry = append(retry, i)
+		}
+
+		check = retry
+	}
+
+	return nil
+}
+
+// storeBatch stores the links of the items at the given indexes and records them in results
+func (lu linkUseCase) storeBatch(ctx context.Context, items []domain.LinkBatchItem, indexes []int,
+	results []domain.LinkBatchResult) error {
+	batch := make([]domain.LinkBatchItem, 0, len(indexes))
+	for _, i := range indexes {
+		batch = append(batch, items[i])
+	}
+
+	var aliasOf func(id int64) (string, error)
+	if lu.idAliases != nil {
+		aliasOf = lu.idAliases.AliasOf
+	}
+
+	var ids []int64
+	var err error
+	for attempt := 1; attempt <= aliasAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+		ids, err = lu.linkRepo.StoreBatch(ctx, batch, aliasOf)
+		cancel()
+
+		// a skipped derived alias burns the ids of the batch, the next attempt gets other ids
+		if err != random.ErrSkipped {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for n, i := range indexes {
+		link := items[i].Link
+		link.ID = ids[n]
+		link.CreatedAt = now
+		if link.Alias == "" && aliasOf != nil {
+			link.Alias, _ = aliasOf(link.ID)
+		}
+
+		results[i] = domain.LinkBatchResult{Link: link}
+	}
+
+	return nil
+}
+
+// CheckBatch validates the links and checks that their custom aliases are free,
+// generated aliases are not reserved so a dry run doesn't use up the alias pool
+func (lu linkUseCase) CheckBatch(ctx context.Context, items []domain.LinkBatchItem) ([]domain.LinkBatchResult, error) {
+	results := make([]domain.LinkBatchResult, len(items))
+
+	check := make([]int, 0, len(items))
+	links := make([]domain.Link, 0, len(items))
+	for i, item := range items {
+		results[i] = domain.LinkBatchResult{Link: item.Link, Err: validate(item.Link)}
+		if results[i].Err == nil && item.Link.Alias != "" {
+			check = append(check, i)
+			links = append(links, item.Link)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	taken, err := lu.linkRepo.TakenAliases(ctx, links)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool, len(check))
+	for n, i := range check {
+		key := fmt.Sprintf("%d/%s", items[i].Link.DomainId, items[i].Link.Alias)
+		if taken[n] || used[key] {
+			results[i].Err = domain.ErrLinkIsExists
+		}
+		used[key] = true
+	}
+
+	return results, nil
+}
+
+// Export reads the links page by page, so the caller can write them out without holding all of them
+func (lu linkUseCase) Export(ctx context.Context, filter domain.LinkFilter, fn func(links []domain.Link) error) error {
+	if filter.Limit <= 0 {
+		filter.Limit = 500
+	}
+
+	var afterId int64
+	for {
+		ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+		links, err := lu.linkRepo.FetchAfter(ctx, filter, afterId)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if len(links) == 0 {
+			return nil
+		}
+
+		if err = fn(links); err != nil {
+			return err
+		}
+
+		if int64(len(links)) < filter.Limit {
+			return nil
+		}
+
+		afterId = links[len(links)-1].ID
+	}
+}
+
+// abortBatch keeps the errors of the failed links and fails the others with ErrBatchAborted
+func abortBatch(results []domain.LinkBatchResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = domain.ErrBatchAborted
+		}
+	}
+}
+
+// targetHash returns the hex sha256 of the canonical target
+func targetHash(target string) string {
+	sum := sha256.Sum256([]byte(query.Canonical(target)))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,200 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/random"
+	"github.com/iambakhodir/short-link/link/usecase"
+	"testing"
+	"time"
+)
+
+// fakeLinkRepository keeps the links in memory, the methods the tests don't use panic
+type fakeLinkRepository struct {
+	domain.LinkRepository
+	links map[int64]domain.Link
+}
+
+func newFakeLinkRepository(links ...domain.Link) *fakeLinkRepository {
+	repo := &fakeLinkRepository{links: make(map[int64]domain.Link)}
+	for _, link := range links {
+		repo.links[link.ID] = link
+	}
+
+	return repo
+}
+
+func (f *fakeLinkRepository) GetById(ctx context.Context, id int64) (domain.Link, error) {
+	link, ok := f.links[id]
+	if !ok {
+		return domain.Link{}, domain.ErrNotFound
+	}
+
+	return link, nil
+}
+
+func (f *fakeLinkRepository) UpdateStatus(ctx context.Context, id int64, from string, to string) error {
+	link, ok := f.links[id]
+	if !ok || link.Status != from || link.DeletedAt.Valid {
+		return domain.ErrConflict
+	}
+
+	link.Status = to
+	f.links[id] = link
+
+	return nil
+}
+
+func (f *fakeLinkRepository) Delete(ctx context.Context, id int64) error {
+	link := f.links[id]
+	link.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	f.links[id] = link
+
+	return nil
+}
+
+func (f *fakeLinkRepository) Restore(ctx context.Context, id int64) error {
+	link, ok := f.links[id]
+	if !ok || !link.DeletedAt.Valid {
+		return domain.ErrNotFound
+	}
+
+	link.DeletedAt = sql.NullTime{}
+	f.links[id] = link
+
+	return nil
+}
+
+// StoreWithIdAlias burns the next id when its alias is skipped like the mysql repository does
+func (f *fakeLinkRepository) StoreWithIdAlias(ctx context.Context, link domain.Link,
+	aliasOf func(id int64) (string, error)) (int64, error) {
+	id := int64(len(f.links) + 1)
+	f.links[id] = domain.Link{ID: id, DeletedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+
+	alias, err := aliasOf(id)
+	if err != nil {
+		return 0, err
+	}
+
+	link.ID = id
+	link.Alias = alias
+	f.links[id] = link
+
+	return id, nil
+}
+
+func TestStoreGeneratedSkipsReservedAliases(t *testing.T) {
+	plain, err := random.NewFeistelGenerator([]byte("secret"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reserved := make(map[string]bool)
+	for id := int64(1); id <= 3; id++ {
+		alias, _ := plain.AliasOf(id)
+		reserved[alias] = true
+	}
+
+	tests := []struct {
+		name    string
+		skip    func(alias string) bool
+		wantId  int64
+		wantErr error
+	}{
+		{"reserved aliases burn their ids", func(alias string) bool { return reserved[alias] }, 4, nil},
+		{"every alias is reserved", func(alias string) bool { return true }, 0, random.ErrSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idAliases, err := random.NewFeistelGenerator([]byte("secret"), "", tt.skip)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			repo := newFakeLinkRepository()
+			lu := usecase.NewLinkUseCase(repo, nil, idAliases, time.Second)
+
+			id, err := lu.StoreGenerated(context.Background(), domain.Link{Target: "https://example.com"}, 0)
+			if err != tt.wantErr || id != tt.wantId {
+				t.Fatalf("StoreGenerated() = %d, %v, want %d, %v", id, err, tt.wantId, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLinkStatusTransitions(t *testing.T) {
+	deleted := sql.NullTime{Time: time.Now(), Valid: true}
+
+	tests := []struct {
+		name       string
+		link       domain.Link
+		disable    bool
+		wantErr    error
+		wantStatus string
+	}{
+		{"disable the active link", domain.Link{ID: 1, Status: domain.LinkStatusActive}, true, nil, domain.LinkStatusDisabled},
+		{"enable the disabled link", domain.Link{ID: 1, Status: domain.LinkStatusDisabled}, false, nil, domain.LinkStatusActive},
+		{"disable the disabled link", domain.Link{ID: 1, Status: domain.LinkStatusDisabled}, true, domain.ErrConflict, domain.LinkStatusDisabled},
+		{"enable the active link", domain.Link{ID: 1, Status: domain.LinkStatusActive}, false, domain.ErrConflict, domain.LinkStatusActive},
+		{"disable the deleted link", domain.Link{ID: 1, Status: domain.LinkStatusActive, DeletedAt: deleted}, true, domain.ErrLinkDeleted, domain.LinkStatusActive},
+		{"enable the deleted link", domain.Link{ID: 1, Status: domain.LinkStatusDisabled, DeletedAt: deleted}, false, domain.ErrLinkDeleted, domain.LinkStatusDisabled},
+		{"disable the missing link", domain.Link{ID: 2, Status: domain.LinkStatusActive}, true, domain.ErrNotFound, domain.LinkStatusActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeLinkRepository(tt.link)
+			lu := usecase.NewLinkUseCase(repo, nil, nil, time.Second)
+
+			var err error
+			if tt.disable {
+				err = lu.Disable(context.Background(), 1)
+			} else {
+				err = lu.Enable(context.Background(), 1)
+			}
+
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if status := repo.links[tt.link.ID].Status; status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestLinkStatusSurvivesTrash(t *testing.T) {
+	repo := newFakeLinkRepository(domain.Link{ID: 1, Status: domain.LinkStatusActive})
+	lu := usecase.NewLinkUseCase(repo, nil, nil, time.Second)
+	ctx := context.Background()
+
+	if err := lu.Disable(ctx, 1); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if err := lu.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if err := lu.Enable(ctx, 1); err != domain.ErrLinkDeleted {
+		t.Fatalf("Enable() of the deleted link error = %v, want %v", err, domain.ErrLinkDeleted)
+	}
+	if got := repo.links[1].LifecycleStatus(time.Now()); got != domain.LinkStatusDeleted {
+		t.Errorf("lifecycle status in the trash = %q, want %q", got, domain.LinkStatusDeleted)
+	}
+
+	if err := lu.Restore(ctx, 1); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := repo.links[1].LifecycleStatus(time.Now()); got != domain.LinkStatusDisabled {
+		t.Errorf("lifecycle status after the restore = %q, want %q", got, domain.LinkStatusDisabled)
+	}
+
+	if err := lu.Enable(ctx, 1); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	if got := repo.links[1].Status; got != domain.LinkStatusActive {
+		t.Errorf("status = %q, want %q", got, domain.LinkStatusActive)
+	}
+}
@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/variant"
+	"time"
+)
+
+type linkVariantsUseCase struct {
+	variantsRepo   domain.LinkVariantsRepository
+	contextTimeout time.Duration
+}
+
+func NewLinkVariantsUseCase(variantsRepo domain.LinkVariantsRepository, timeout time.Duration) domain.LinkVariantsUseCase {
+	return &linkVariantsUseCase{variantsRepo: variantsRepo, contextTimeout: timeout}
+}
+
+func (lv linkVariantsUseCase) FetchByLinkId(ctx context.Context, linkId int64) ([]domain.LinkVariant, error) {
+	ctx, cancel := context.WithTimeout(ctx, lv.contextTimeout)
+	defer cancel()
+
+	return lv.variantsRepo.FetchByLinkId(ctx, linkId)
+}
+
+func (lv linkVariantsUseCase) FetchWithVisitsByLinkId(ctx context.Context, linkId int64) ([]domain.LinkVariant, error) {
+	ctx, cancel := context.WithTimeout(ctx, lv.contextTimeout)
+	defer cancel()
+
+	return lv.variantsRepo.FetchWithVisitsByLinkId(ctx, linkId)
+}
+
+func (lv linkVariantsUseCase) Replace(ctx context.Context, linkId int64, variants []domain.LinkVariant) error {
+	ctx, cancel := context.WithTimeout(ctx, lv.contextTimeout)
+	defer cancel()
+
+	return lv.variantsRepo.Replace(ctx, linkId, variants)
+}
+
+// Pick chooses the variant of the visitor identified by key
+func (lv linkVariantsUseCase) Pick(variants []domain.LinkVariant, key string) (domain.LinkVariant, bool) {
+	return variant.Pick(variants, key)
+}
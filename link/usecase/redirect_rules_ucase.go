@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/geoip"
+	"github.com/iambakhodir/short-link/domain/rules"
+	"github.com/iambakhodir/short-link/domain/useragent"
+	"time"
+)
+
+type redirectRulesUseCase struct {
+	rulesRepo      domain.RedirectRulesRepository
+	geo            *geoip.DB
+	contextTimeout time.Duration
+}
+
+// NewRedirectRulesUseCase creates the rules use case, geo may be nil when no GeoIP database is configured
+func NewRedirectRulesUseCase(rulesRepo domain.RedirectRulesRepository, geo *geoip.DB, timeout time.Duration) domain.RedirectRulesUseCase {
+	return &redirectRulesUseCase{rulesRepo: rulesRepo, geo: geo, contextTimeout: timeout}
+}
+
+func (r redirectRulesUseCase) FetchByLinkId(ctx context.Context, linkId int64) ([]domain.RedirectRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	return r.rulesRepo.FetchByLinkId(ctx, linkId)
+}
+
+func (r redirectRulesUseCase) GetById(ctx context.Context, id int64) (domain.RedirectRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	return r.rulesRepo.GetById(ctx, id)
+}
+
+func (r redirectRulesUseCase) Update(ctx context.Context, rule domain.RedirectRule) (int64, error) {
+	if err := validateCondition(rule.Condition); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	existedRule, err := r.rulesRepo.GetById(ctx, rule.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if existedRule.LinkId != rule.LinkId {
+		return 0, domain.ErrNotFound
+	}
+
+	rule.UpdatedAt = time.Now()
+
+	return r.rulesRepo.Update(ctx, rule)
+}
+
+func (r redirectRulesUseCase) Store(ctx context.Context, rule domain.RedirectRule) (int64, error) {
+	if err := validateCondition(rule.Condition); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	return r.rulesRepo.Store(ctx, rule)
+}
+
+func (r redirectRulesUseCase) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	_, err := r.rulesRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return r.rulesRepo.Delete(ctx, id)
+}
+
+// Match returns the first rule of the link which matches the visitor
+func (r redirectRulesUseCase) Match(ctx context.Context, linkId int64, visitor domain.RuleVisitor) (domain.RedirectRule, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	list, err := r.rulesRepo.FetchByLinkId(ctx, linkId)
+	if err != nil {
+		return domain.RedirectRule{}, false, err
+	}
+
+	if len(list) == 0 {
+		return domain.RedirectRule{}, false, nil
+	}
+
+	info := useragent.Parse(visitor.UserAgent)
+	req := rules.Request{
+		Device:    info.Device,
+		OS:        info.OS,
+		Languages: rules.ParseAcceptLanguage(visitor.AcceptLanguage),
+		Country:   r.geo.Country(visitor.IP),
+		Time:      visitor.Time,
+		Query:     visitor.Query,
+	}
+
+	rule, ok := rules.Evaluate(list, req)
+
+	return rule, ok, nil
+}
+
+func validateCondition(cond domain.RuleCondition) error {
+	if cond.TimeWindow == nil {
+		return nil
+	}
+
+	if err := rules.ValidateTimeWindow(*cond.TimeWindow); err != nil {
+		return domain.ErrBadParamInput
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/lru"
+	"github.com/iambakhodir/short-link/domain/referrer"
+	"time"
+)
+
+const maxReferrerLength = 2048
+
+// resolvedReferrers is how many referrer ids are kept in memory, the header is sent by the visitor
+// so the cache is bounded
+const resolvedReferrers = 10000
+
+type referrersUseCase struct {
+	referrersRepo  domain.ReferrersRepository
+	stripQuery     bool
+	contextTimeout time.Duration
+
+	// resolved keeps referrer ids by hash of the canonical url
+	resolved *lru.Cache[string, int64]
+}
+
+func NewReferrersUseCase(referrersRepo domain.ReferrersRepository, stripQuery bool, timeout time.Duration) domain.ReferrersUseCase {
+	return &referrersUseCase{
+		referrersRepo:  referrersRepo,
+		stripQuery:     stripQuery,
+		contextTimeout: timeout,
+		resolved:       lru.New[string, int64](resolvedReferrers),
+	}
+}
+
+func (r *referrersUseCase) GetById(ctx context.Context, id int64) (domain.Referrers, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	return r.referrersRepo.GetById(ctx, id)
+}
+
+// Resolve canonicalizes the raw Referer header and returns id of the stored referrer.
+// Empty or invalid referrers (direct visits) resolve to 0.
+func (r *referrersUseCase) Resolve(ctx context.Context, raw string) (int64, error) {
+	canonical, ok := referrer.Canonicalize(raw, r.stripQuery)
+	if !ok {
+		return 0, nil
+	}
+
+	if len(canonical) > maxReferrerLength {
+		canonical = canonical[:maxReferrerLength]
+	}
+
+	sum := sha1.Sum([]byte(canonical))
+	hash := hex.EncodeToString(sum[:])
+
+	if id, ok := r.resolved.Get(hash); ok {
+		return id, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	id, err := r.referrersRepo.FirstOrCreate(ctx, domain.Referrers{Hash: hash, Url: canonical})
+	if err != nil {
+		return 0, err
+	}
+
+	r.resolved.Add(hash, id)
+
+	return id, nil
+}
+
+func (r *referrersUseCase) FetchTopByLinkId(ctx context.Context, linkId int64, limit int64) ([]domain.VisitsStat, error) {
+	if limit == 0 {
+		limit = 10
+	} else if limit > 100 {
+		limit = 100
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.contextTimeout)
+	defer cancel()
+
+	return r.referrersRepo.FetchTopByLinkId(ctx, linkId, limit)
+}
+
+// Enrich sets ReferrerId of the visit from its raw Referer header
+func (r *referrersUseCase) Enrich(ctx context.Context, visit *domain.Visits) error {
+	if visit.ReferrerId != 0 {
+		return nil
+	}
+
+	id, err := r.Resolve(ctx, visit.Referrer)
+	if err != nil {
+		return err
+	}
+
+	visit.ReferrerId = id
+
+	return nil
+}
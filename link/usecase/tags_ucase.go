@@ -45,6 +45,13 @@ func (t tagsUseCase) FetchByLinkId(ctx context.Context, linkId int64) ([]domain.
 	return res, nil
 }
 
+func (t tagsUseCase) FetchByLinkIds(ctx context.Context, linkIds []int64) (map[int64][]domain.Tags, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
+	defer cancel()
+
+	return t.tagsRepo.FetchByLinkIds(ctx, linkIds)
+}
+
 func (t tagsUseCase) GetById(ctx context.Context, id int64) (domain.Tags, error) {
 	ctx, cancel := context.WithTimeout(ctx, t.contextTimeout)
 	defer cancel()
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/iambakhodir/short-link/domain/lru"
+	"github.com/iambakhodir/short-link/domain/useragent"
+	"strings"
+	"time"
+)
+
+const maxUserAgentLength = 512
+
+// resolvedUserAgents is how many user agent ids are kept in memory, the header is sent by the visitor
+// so the cache is bounded
+const resolvedUserAgents = 10000
+
+type userAgentsUseCase struct {
+	userAgentsRepo domain.UserAgentsRepository
+	browsersRepo   domain.BrowsersRepository
+	devicesRepo    domain.DevicesRepository
+	contextTimeout time.Duration
+
+	// resolved keeps user agent ids by hash, the same headers repeat on almost every visit
+	resolved *lru.Cache[string, int64]
+}
+
+func NewUserAgentsUseCase(userAgentsRepo domain.UserAgentsRepository, browsersRepo domain.BrowsersRepository,
+	devicesRepo domain.DevicesRepository, timeout time.Duration) domain.UserAgentsUseCase {
+	return &userAgentsUseCase{
+		userAgentsRepo: userAgentsRepo,
+		browsersRepo:   browsersRepo,
+		devicesRepo:    devicesRepo,
+		contextTimeout: timeout,
+		resolved:       lru.New[string, int64](resolvedUserAgents),
+	}
+}
+
+func (u *userAgentsUseCase) GetById(ctx context.Context, id int64) (domain.UserAgents, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	return u.userAgentsRepo.GetById(ctx, id)
+}
+
+// Resolve parses the raw User-Agent header and returns id of the stored user agent,
+// creating the user agent, its browser and device when they are seen for the first time.
+func (u *userAgentsUseCase) Resolve(ctx context.Context, raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > maxUserAgentLength {
+		raw = raw[:maxUserAgentLength]
+	}
+
+	sum := sha1.Sum([]byte(raw))
+	hash := hex.EncodeToString(sum[:])
+
+	if id, ok := u.resolved.Get(hash); ok {
+		return id, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	info := useragent.Parse(raw)
+
+	browserId, err := u.browsersRepo.FirstOrCreate(ctx, domain.Browsers{Name: info.Browser, Version: info.BrowserVersion})
+	if err != nil {
+		return 0, err
+	}
+
+	deviceId, err := u.devicesRepo.FirstOrCreate(ctx, domain.Devices{Name: info.Device})
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := u.userAgentsRepo.FirstOrCreate(ctx, domain.UserAgents{
+		BrowserId: browserId,
+		DeviceId:  deviceId,
+		Hash:      hash,
+		Name:      raw,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	u.resolved.Add(hash, id)
+
+	return id, nil
+}
+
+// Enrich sets UserAgentId of the visit from its raw User-Agent header
+func (u *userAgentsUseCase) Enrich(ctx context.Context, visit *domain.Visits) error {
+	if visit.UserAgentId != 0 {
+		return nil
+	}
+
+	id, err := u.Resolve(ctx, visit.UserAgent)
+	if err != nil {
+		return err
+	}
+
+	visit.UserAgentId = id
+
+	return nil
+}
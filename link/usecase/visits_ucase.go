@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"github.com/iambakhodir/short-link/domain"
+	"time"
+)
+
+type visitsUseCase struct {
+	visitsRepo     domain.VisitsRepository
+	contextTimeout time.Duration
+}
+
+func NewVisitsUseCase(visitsRepo domain.VisitsRepository, timeout time.Duration) domain.VisitsUseCase {
+	return &visitsUseCase{visitsRepo: visitsRepo, contextTimeout: timeout}
+}
+
+func (v visitsUseCase) Fetch(ctx context.Context, limit int64) ([]domain.Visits, error) {
+	if limit == 0 {
+		limit = 10
+	} else if limit > 100 {
+		limit = 100
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.contextTimeout)
+	defer cancel()
+
+	res, err := v.visitsRepo.Fetch(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (v visitsUseCase) GetById(ctx context.Context, id int64) (domain.Visits, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.contextTimeout)
+	defer cancel()
+
+	res, err := v.visitsRepo.GetById(ctx, id)
+	if err != nil {
+		return domain.Visits{}, err
+	}
+
+	return res, nil
+}
+
+func (v visitsUseCase) Update(ctx context.Context, visit domain.Visits) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.contextTimeout)
+	defer cancel()
+
+	existedVisit, err := v.visitsRepo.GetById(ctx, visit.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if existedVisit == (domain.Visits{}) {
+		return 0, domain.ErrNotFound
+	}
+
+	visit.UpdatedAt = time.Now()
+
+	return v.visitsRepo.Update(ctx, visit)
+}
+
+func (v visitsUseCase) GetByAlias(ctx context.Context, alias string) (domain.Visits, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.contextTimeout)
+	defer cancel()
+
+	res, err := v.visitsRepo.GetByAlias(ctx, alias)
+	if err != nil {
+		return domain.Visits{}, err
+	}
+
+	return res, nil
+}
+
+func (v visitsUseCase) Store(ctx context.Context, visit domain.Visits) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.contextTimeout)
+	defer cancel()
+
+	return v.visitsRepo.Store(ctx, visit)
+}
+
+func (v visitsUseCase) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, v.contextTimeout)
+	defer cancel()
+
+	existedVisit, err := v.visitsRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if existedVisit == (domain.Visits{}) {
+		return domain.ErrNotFound
+	}
+
+	return v.visitsRepo.Delete(ctx, id)
+}
+
+func (v visitsUseCase) FetchBrowsersStat(ctx context.Context, linkId int64, limit int64) ([]domain.VisitsStat, error) {
+	if limit == 0 {
+		limit = 10
+	} else if limit > 100 {
+		limit = 100
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.contextTimeout)
+	defer cancel()
+
+	return v.visitsRepo.FetchBrowsersStat(ctx, linkId, limit)
+}
+
+func (v visitsUseCase) FetchDevicesStat(ctx context.Context, linkId int64, limit int64) ([]domain.VisitsStat, error) {
+	if limit == 0 {
+		limit = 10
+	} else if limit > 100 {
+		limit = 100
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.contextTimeout)
+	defer cancel()
+
+	return v.visitsRepo.FetchDevicesStat(ctx, linkId, limit)
+}
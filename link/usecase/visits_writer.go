@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"github.com/iambakhodir/short-link/domain"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// VisitsWriterConfig represent the settings of the buffered visit's writer
+type VisitsWriterConfig struct {
+	QueueSize      int
+	Workers        int
+	BatchSize      int
+	FlushInterval  time.Duration
+	EnqueueTimeout time.Duration
+}
+
+type visitsWriter struct {
+	visitsRepo     domain.VisitsRepository
+	enrichers      []domain.VisitsEnricher
+	contextTimeout time.Duration
+	config         VisitsWriterConfig
+
+	queue  chan domain.Visits
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	queued  int64
+	written int64
+	dropped int64
+	failed  int64
+}
+
+// NewVisitsWriter starts the background workers which store queued visits in batches.
+// A batch is flushed when it reaches BatchSize or when FlushInterval passes.
+// Enrichers fill the derived fields of every visit right before the batch is stored.
+func NewVisitsWriter(visitsRepo domain.VisitsRepository, config VisitsWriterConfig, timeout time.Duration,
+	enrichers ...domain.VisitsEnricher) domain.VisitsWriter {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 10000
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+
+	w := &visitsWriter{
+		visitsRepo:     visitsRepo,
+		enrichers:      enrichers,
+		contextTimeout: timeout,
+		config:         config,
+		queue:          make(chan domain.Visits, config.QueueSize),
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		w.wg.Add(1)
+		go w.work()
+	}
+
+	return w
+}
+
+// Write queues the visit. When the queue is full it waits up to EnqueueTimeout
+// and then drops the visit with domain.ErrQueueFull.
+func (w *visitsWriter) Write(visit domain.Visits) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return domain.ErrWriterClosed
+	}
+
+	select {
+	case w.queue <- visit:
+		atomic.AddInt64(&w.queued, 1)
+		return nil
+	default:
+	}
+
+	if w.config.EnqueueTimeout > 0 {
+		timer := time.NewTimer(w.config.EnqueueTimeout)
+		defer timer.Stop()
+
+		select {
+		case w.queue <- visit:
+			atomic.AddInt64(&w.queued, 1)
+			return nil
+		case <-timer.C:
+		}
+	}
+
+	atomic.AddInt64(&w.dropped, 1)
+	return domain.ErrQueueFull
+}
+
+func (w *visitsWriter) Stats() domain.VisitsWriterStats {
+	return domain.VisitsWriterStats{
+		Queued:  atomic.LoadInt64(&w.queued),
+		Written: atomic.LoadInt64(&w.written),
+		Dropped: atomic.LoadInt64(&w.dropped),
+		Failed:  atomic.LoadInt64(&w.failed),
+	}
+}
+
+// Close stops accepting visits and waits until the queued ones are stored
+func (w *visitsWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *visitsWriter) work() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.Visits, 0, w.config.BatchSize)
+	for {
+		select {
+		case visit, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+
+			batch = append(batch, visit)
+			if len(batch) >= w.config.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (w *visitsWriter) flush(batch []domain.Visits) {
+	if len(batch) == 0 {
+		return
+	}
+
+	for i := range batch {
+		for _, enricher := range w.enrichers {
+			if err := enricher.Enrich(context.Background(), &batch[i]); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.contextTimeout)
+	defer cancel()
+
+	err := w.visitsRepo.StoreBatch(ctx, batch)
+	if err != nil {
+		logrus.Error(err)
+		atomic.AddInt64(&w.failed, int64(len(batch)))
+		return
+	}
+
+	atomic.AddInt64(&w.written, int64(len(batch)))
+}